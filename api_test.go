@@ -0,0 +1,215 @@
+package shredder
+
+import (
+    "flag"
+    "go/importer"
+    "go/token"
+    "go/types"
+    "io"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+)
+
+var updateAPI = flag.Bool("update", false, "Rewrite testdata/api.txt with the current exported API")
+
+// The packages whose exported API is held stable
+var stablePackages = []string{"shredder", "shredder/faultfs"}
+
+// loadPackages type-checks the stable packages from the export data the
+// go command builds for them, as the compiler sees them
+func loadPackages(t *testing.T) []*types.Package {
+    args := append([]string{"list", "-deps", "-export", "-f", "{{.ImportPath}}={{.Export}}"}, stablePackages...)
+    out, err := exec.Command("go", args...).Output()
+    if err != nil {
+        t.Fatalf("Could not list export data: %v", err)
+    }
+
+    exports := make(map[string]string)
+    for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+        path, file, _ := strings.Cut(line, "=")
+        exports[path] = file
+    }
+
+    imports := importer.ForCompiler(token.NewFileSet(), "gc", func(path string) (io.ReadCloser, error) {
+        return os.Open(exports[path])
+    })
+
+    var packages []*types.Package
+    for _, path := range stablePackages {
+        pkg, err := imports.Import(path)
+        if err != nil {
+            t.Fatalf("Could not load %s: %v", path, err)
+        }
+        packages = append(packages, pkg)
+    }
+    return packages
+}
+
+// exportedAPI lists every exported declaration, one per line, so that
+// any change a caller could notice alters or removes a line while
+// additions only add lines. Struct fields and methods get lines of
+// their own, as adding them is compatible; an interface's methods share
+// its line, as adding one breaks every implementation.
+func exportedAPI(t *testing.T) []string {
+    var api []string
+    for _, pkg := range loadPackages(t) {
+        qualifier := func(other *types.Package) string {
+            if other == pkg {
+                return ""
+            }
+            return other.Name()
+        }
+        typeString := func(typ types.Type) string {
+            return types.TypeString(typ, qualifier)
+        }
+        add := func(line string) {
+            api = append(api, pkg.Path()+": "+line)
+        }
+
+        scope := pkg.Scope()
+        for _, name := range scope.Names() {
+            switch obj := scope.Lookup(name).(type) {
+            case *types.Const:
+                if obj.Exported() {
+                    add("const " + name + " " + typeString(obj.Type()) + " = " + obj.Val().ExactString())
+                }
+            case *types.Var:
+                if obj.Exported() {
+                    add("var " + name + " " + typeString(obj.Type()))
+                }
+            case *types.Func:
+                if obj.Exported() {
+                    add("func " + name + signatureString(obj.Type().(*types.Signature), typeString))
+                }
+            case *types.TypeName:
+                if obj.Exported() {
+                    addType(obj, typeString, add)
+                }
+            }
+        }
+    }
+
+    sort.Strings(api)
+    return api
+}
+
+func addType(obj *types.TypeName, typeString func(types.Type) string, add func(string)) {
+    name := obj.Name()
+    switch underlying := obj.Type().Underlying().(type) {
+    case *types.Struct:
+        add("type " + name + " struct")
+        for i := 0; i < underlying.NumFields(); i++ {
+            field := underlying.Field(i)
+            if field.Exported() {
+                add("field " + name + "." + field.Name() + " " + typeString(field.Type()))
+            }
+        }
+    case *types.Interface:
+        var methods []string
+        for i := 0; i < underlying.NumMethods(); i++ {
+            method := underlying.Method(i)
+            methods = append(methods, method.Name()+signatureString(method.Type().(*types.Signature), typeString))
+        }
+        sort.Strings(methods)
+        add("type " + name + " interface{" + strings.Join(methods, "; ") + "}")
+    case *types.Signature:
+        add("type " + name + " func" + signatureString(underlying, typeString))
+    default:
+        add("type " + name + " " + typeString(underlying))
+    }
+
+    named, ok := obj.Type().(*types.Named)
+    if !ok {
+        return
+    }
+    for i := 0; i < named.NumMethods(); i++ {
+        method := named.Method(i)
+        if !method.Exported() {
+            continue
+        }
+
+        signature := method.Type().(*types.Signature)
+        receiver := name
+        if _, pointer := signature.Recv().Type().(*types.Pointer); pointer {
+            receiver = "*" + name
+        }
+        add("method (" + receiver + ") " + method.Name() + signatureString(signature, typeString))
+    }
+}
+
+// signatureString writes a signature without parameter names, since
+// renaming them does not affect callers
+func signatureString(signature *types.Signature, typeString func(types.Type) string) string {
+    tuple := func(vars *types.Tuple, variadic bool) []string {
+        var names []string
+        for i := 0; i < vars.Len(); i++ {
+            typ := typeString(vars.At(i).Type())
+            if variadic && i == vars.Len()-1 {
+                typ = "..." + strings.TrimPrefix(typ, "[]")
+            }
+            names = append(names, typ)
+        }
+        return names
+    }
+
+    result := "(" + strings.Join(tuple(signature.Params(), signature.Variadic()), ", ") + ")"
+    switch results := tuple(signature.Results(), false); len(results) {
+    case 0:
+    case 1:
+        result += " " + results[0]
+    default:
+        result += " (" + strings.Join(results, ", ") + ")"
+    }
+    return result
+}
+
+func TestAPIIsCompatible(t *testing.T) {
+    // Given
+    golden := filepath.Join("testdata", "api.txt")
+    current := exportedAPI(t)
+
+    if *updateAPI {
+        os.MkdirAll("testdata", 0755)
+        err := os.WriteFile(golden, []byte(strings.Join(current, "\n")+"\n"), 0644)
+        if err != nil {
+            t.Fatalf("Could not write %s: %v", golden, err)
+        }
+    }
+
+    content, err := os.ReadFile(golden)
+    if err != nil {
+        t.Fatalf("Could not read %s: %v", golden, err)
+    }
+
+    // When
+    have := map[string]bool{}
+    for _, line := range current {
+        have[line] = true
+    }
+
+    var removed, added []string
+    recorded := map[string]bool{}
+    for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+        recorded[line] = true
+        if !have[line] {
+            removed = append(removed, line)
+        }
+    }
+    for _, line := range current {
+        if !recorded[line] {
+            added = append(added, line)
+        }
+    }
+
+    // Then
+    for _, line := range removed {
+        t.Errorf("Breaking API change, removed or changed: %s", line)
+    }
+    for _, line := range added {
+        t.Errorf("API addition not recorded, run with -update: %s", line)
+    }
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package shredder
+
+import "io"
+
+// isAppendMode cannot inspect handle flags on this platform
+func isAppendMode(writer io.Writer) bool {
+    return false
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package shredder
+
+import (
+    "io"
+    "syscall"
+)
+
+// isAppendMode reports whether writer is a file descriptor opened with
+// O_APPEND. Writers without a descriptor are assumed not to be.
+func isAppendMode(writer io.Writer) bool {
+    file, ok := writer.(interface {
+        Fd() uintptr
+    })
+    if !ok {
+        return false
+    }
+
+    flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_GETFL, 0)
+    if errno != 0 {
+        return false
+    }
+
+    return flags&syscall.O_APPEND != 0
+}
@@ -0,0 +1,394 @@
+package shredder
+
+import (
+    "context"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "sync"
+)
+
+// ShredDir shreds every regular file under root, several at a time.
+// Symlinks and special files are skipped rather than followed, and a
+// root which is itself a symlink is rejected with ErrSymlinkRoot. AppFs
+// must implement afero.Lstater for symlinks to be recognised, so any
+// other filesystem is rejected with ErrNoLstat. Without opts.Force,
+// directories marked as protected are left alone and reported as
+// ErrProtected failures, and a protected root is refused outright. With
+// opts.Remove each file is deleted after shredding, and then any
+// directories, root included, that were left empty. A failure on one
+// file does not stop the rest; all failures are returned together.
+// A tree deeper than opts.MaxDepth or with more than opts.MaxEntries
+// entries stops the walk with ErrTooDeep or ErrTooManyEntries, though
+// files found before then are still shredded; with opts.PreScan the
+// limits are checked before anything is. Hard-linked names within the
+// tree are shredded as one file. Set opts.Results to stream a result for
+// each file instead of collecting failures.
+func ShredDir(root string, opts Options) error {
+    return ShredDirContext(context.Background(), root, opts)
+}
+
+// ShredDirContext is ShredDir, stopping the walk with the context's
+// cause if it is cancelled. Files already being shredded stop at their
+// next chunk, with the cause in their Result, and no directories are
+// removed.
+func ShredDirContext(ctx context.Context, root string, opts Options) error {
+    if opts.Verification != nil {
+        return fmt.Errorf("Error shredding %s: Options.Verification cannot be shared by every file; "+
+            "set Options.Results to receive each file's report", root)
+    }
+
+    // Offset applies to every file in the tree, so removing them would
+    // leave the start of each one intact
+    if opts.Remove {
+        err := checkRemovable(opts)
+        if err != nil {
+            return err
+        }
+    }
+
+    err := checkRoot(root)
+    if err != nil {
+        return err
+    }
+
+    err = checkProtected(root, opts)
+    if err != nil {
+        return err
+    }
+
+    var tree *treeProgress
+    if opts.PreScan {
+        tree, err = newTreeProgress(ctx, root, opts)
+        if err != nil {
+            return fmt.Errorf("Error scanning %s: %w", root, err)
+        }
+    }
+
+    var dirs []string
+    targets := make(chan []string)
+    errs := make(chan error)
+
+    // Results are written one at a time, so writers need no locking of
+    // their own. Only the first error writing them is kept.
+    var results sync.Mutex
+    var shredded, failed int
+    var resultErr error
+    writeResult := func(names []string, err error, report *VerificationReport, applied appliedPasses) {
+        results.Lock()
+        defer results.Unlock()
+
+        shredded++
+        if err != nil {
+            failed++
+        }
+
+        result := Result{
+            Path:          names[0],
+            Links:         names[1:],
+            Err:           err,
+            Verification:  report,
+            Passes:        applied.count,
+            PassReduction: applied.reduction,
+        }
+
+        writeErr := opts.Results.WriteResult(ctx, result)
+        if writeErr != nil && resultErr == nil {
+            resultErr = fmt.Errorf("Error writing result for %s: %w", names[0], writeErr)
+        }
+    }
+
+    // Each file is timed and measured on its own and added to the
+    // caller's totals here, as workers finish files at the same time
+    var totals sync.Mutex
+    mergeTotals := func(fileOpts Options) {
+        totals.Lock()
+        defer totals.Unlock()
+
+        if opts.Timings != nil {
+            opts.Timings.merge(*fileOpts.Timings)
+        }
+        if opts.Usage != nil {
+            opts.Usage.merge(*fileOpts.Usage)
+        }
+    }
+
+    if opts.Usage != nil {
+        defer opts.Usage.addCPUSince(processCPUTime())
+    }
+
+    var workers sync.WaitGroup
+    for i := 0; i < opts.workers(); i++ {
+        workers.Add(1)
+        go func() {
+            defer workers.Done()
+            for names := range targets {
+                fileOpts := opts
+                var report *VerificationReport
+                if opts.Verify && opts.Results != nil {
+                    report = &VerificationReport{}
+                    fileOpts.Verification = report
+                }
+
+                var applied appliedPasses
+                fileOpts.applied = &applied
+
+                if tree != nil && opts.Progress != nil {
+                    fileOpts.Progress = tree.forFile(opts.Progress)
+                }
+
+                if opts.Timings != nil {
+                    fileOpts.Timings = &StageTimings{}
+                }
+                if opts.Usage != nil {
+                    fileOpts.Usage = &ResourceUsage{}
+                }
+
+                err := shredNames(ctx, names, fileOpts)
+                mergeTotals(fileOpts)
+
+                if tree != nil {
+                    tree.fileDone()
+                }
+
+                if opts.Results != nil {
+                    writeResult(names, err, report, applied)
+                } else if err != nil {
+                    errs <- fmt.Errorf("Error shredding %s: %w", strings.Join(names, ", "), err)
+                }
+            }
+        }()
+    }
+
+    var failures []error
+    collected := make(chan struct{})
+    go func() {
+        for err := range errs {
+            failures = append(failures, err)
+        }
+        close(collected)
+    }()
+
+    // Names for the same multiply-linked file are gathered until the walk
+    // is over, so its contents are overwritten only once
+    linked := make(map[fileID][]string)
+    var linkedOrder []fileID
+
+    limits := newWalkLimits(root, opts)
+    walkErr := afero.Walk(AppFs, root, func(path string, info os.FileInfo, err error) error {
+        if err := interrupted(ctx); err != nil {
+            return err
+        }
+
+        if err := limits.check(path); err != nil {
+            return err
+        }
+
+        if err != nil {
+            errs <- fmt.Errorf("Error walking %s: %w", path, err)
+            return nil
+        }
+
+        if info.IsDir() {
+            err := checkProtected(path, opts)
+            if err != nil {
+                errs <- err
+                return filepath.SkipDir
+            }
+
+            dirs = append(dirs, path)
+            return nil
+        }
+
+        if !info.Mode().IsRegular() || (opts.Match != nil && !opts.Match.Match(path, info)) {
+            return nil
+        }
+
+        if id, ok := linkedFileID(info); ok {
+            if _, seen := linked[id]; !seen {
+                linkedOrder = append(linkedOrder, id)
+            }
+            linked[id] = append(linked[id], path)
+            return nil
+        }
+
+        targets <- []string{path}
+        return nil
+    })
+
+    // A walk that stopped early was cancelled or broke a limit, so shred
+    // nothing more
+    for _, id := range linkedOrder {
+        if walkErr != nil || interrupted(ctx) != nil {
+            break
+        }
+        targets <- linked[id]
+    }
+
+    close(targets)
+    workers.Wait()
+
+    // Directories were walked parents first, so go backwards to empty
+    // the deepest ones before their parents
+    if opts.Remove && walkErr == nil && interrupted(ctx) == nil {
+        for i := len(dirs) - 1; i >= 0; i-- {
+            err := removeIfEmpty(dirs[i])
+            if err != nil {
+                errs <- fmt.Errorf("Error removing directory %s: %w", dirs[i], err)
+            }
+        }
+    }
+
+    close(errs)
+    <-collected
+
+    if walkErr != nil {
+        failures = append(failures, walkErr)
+    }
+
+    if failed > 0 {
+        failures = append(failures, fmt.Errorf("%w: %d of %d failed", ErrFilesFailed, failed, shredded))
+    }
+
+    if resultErr != nil {
+        failures = append(failures, resultErr)
+    }
+
+    return errors.Join(failures...)
+}
+
+// shredNames shreds one file found under one or more names. The
+// contents are overwritten once; with opts.Remove every name goes.
+func shredNames(ctx context.Context, names []string, opts Options) error {
+    if len(names) > 1 && opts.Remove {
+        return ShredLinksContext(ctx, names, opts)
+    }
+
+    return ShredContext(ctx, names[0], opts)
+}
+
+func (opts Options) workers() int {
+    if opts.Workers > 0 {
+        return opts.Workers
+    }
+
+    return runtime.GOMAXPROCS(0)
+}
+
+// removeShredded truncates an already-shredded file, renames it to a
+// random name in the same directory and deletes it, so its size and
+// name are gone as well as its contents
+func removeShredded(pathToFile string) error {
+    if emergencyStopped.Load() {
+        return ErrEmergencyStop
+    }
+
+    file, err := AppFs.OpenFile(pathToFile, os.O_WRONLY, 0644)
+    if err != nil {
+        return wrapError(ErrOpen, err)
+    }
+
+    err = file.Truncate(0)
+    if err == nil {
+        err = file.Sync()
+    }
+    closeErr := file.Close()
+    if err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        return wrapError(ErrRemove, err)
+    }
+
+    name, err := generateRandomBytes(16)
+    if err != nil {
+        return err
+    }
+
+    anonymousPath := filepath.Join(filepath.Dir(pathToFile), hex.EncodeToString(name))
+    err = AppFs.Rename(pathToFile, anonymousPath)
+    if err != nil {
+        return wrapError(ErrRemove, err)
+    }
+
+    err = AppFs.Remove(anonymousPath)
+    if err != nil {
+        return wrapError(ErrRemove, err)
+    }
+
+    return nil
+}
+
+// walkLimits enforces opts.MaxDepth and opts.MaxEntries on a walk of
+// the tree under root
+type walkLimits struct {
+    root       string
+    maxDepth   int
+    maxEntries int
+    entries    int
+}
+
+func newWalkLimits(root string, opts Options) *walkLimits {
+    return &walkLimits{root: root, maxDepth: opts.MaxDepth, maxEntries: opts.MaxEntries}
+}
+
+// check counts path as one more entry, and fails if it breaks a limit.
+// Root itself is not counted, and entries directly under it are one
+// level deep.
+func (limits *walkLimits) check(path string) error {
+    relative, err := filepath.Rel(limits.root, path)
+    if err != nil || relative == "." {
+        return nil
+    }
+
+    limits.entries++
+    if limits.maxEntries > 0 && limits.entries > limits.maxEntries {
+        return fmt.Errorf("%w: stopped at %s after %d entries", ErrTooManyEntries, path, limits.maxEntries)
+    }
+
+    depth := strings.Count(relative, string(filepath.Separator)) + 1
+    if limits.maxDepth > 0 && depth > limits.maxDepth {
+        return fmt.Errorf("%w: %s is %d levels below %s", ErrTooDeep, path, depth, limits.root)
+    }
+
+    return nil
+}
+
+// removeIfEmpty removes a directory only if nothing is left in it, such
+// as files the matcher excluded
+func removeIfEmpty(dir string) error {
+    empty, err := afero.IsEmpty(AppFs, dir)
+    if err != nil || !empty {
+        return err
+    }
+
+    return AppFs.Remove(dir)
+}
+
+// checkRoot refuses a root which is a symlink. The walk would not follow
+// it, so nothing would be shredded, and quietly doing nothing is the
+// wrong answer to a request to destroy data. It also refuses a
+// filesystem that cannot lstat, as afero.Walk would then follow every
+// symlink in the tree and shred whatever they point to.
+func checkRoot(root string) error {
+    lstater, ok := AppFs.(afero.Lstater)
+    if !ok {
+        return fmt.Errorf("%w: %s", ErrNoLstat, AppFs.Name())
+    }
+
+    info, _, err := lstater.LstatIfPossible(root)
+    if err != nil {
+        return wrapError(ErrStat, err)
+    }
+
+    if info.Mode()&os.ModeSymlink != 0 {
+        return fmt.Errorf("%w: %s", ErrSymlinkRoot, root)
+    }
+
+    return nil
+}
@@ -0,0 +1,343 @@
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "sync"
+    "testing"
+    "time"
+)
+
+var testTree = map[string]string{
+    "/tree/a.txt":           "Some bytes that need replacing",
+    "/tree/b.log":           "More bytes that need replacing",
+    "/tree/sub/c.txt":       "Nested bytes that need replacing",
+    "/tree/sub/deeper/d.db": "Deeply nested bytes that need replacing",
+}
+
+func createTestTree(t *testing.T) {
+    for path, content := range testTree {
+        err := afero.WriteFile(AppFs, path, []byte(content), 0644)
+        if err != nil {
+            t.Fatalf("Could not create test tree: %v", err)
+        }
+    }
+}
+
+func TestShredDirOverwritesEveryFile(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    for path, original := range testTree {
+        content, _ := afero.ReadFile(AppFs, path)
+        if len(content) != len(original) || bytes.Equal(content, []byte(original)) {
+            t.Errorf("Test failed, expected %s to be overwritten, got:  '%q'", path, content)
+        }
+    }
+}
+
+func TestShredDirWithRemoveLeavesNothing(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if exists, _ := afero.Exists(AppFs, "/tree"); exists {
+        t.Errorf("Test failed, expected the whole tree to be removed")
+    }
+}
+
+func TestShredDirOnlyShredsMatchingFiles(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true, Match: MustNameGlob("*.txt")})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    for path, original := range testTree {
+        exists, _ := afero.Exists(AppFs, path)
+        if shouldRemain := !MustNameGlob("*.txt").Match(path, nil); exists != shouldRemain {
+            t.Errorf("Test failed for %s, expected to exist: '%t', got:  '%t'", path, shouldRemain, exists)
+        }
+        if exists {
+            content, _ := afero.ReadFile(AppFs, path)
+            if string(content) != original {
+                t.Errorf("Test failed, expected %s to be untouched, got:  '%q'", path, content)
+            }
+        }
+    }
+
+    // Directories that still hold unmatched files are kept
+    if exists, _ := afero.Exists(AppFs, "/tree/sub/deeper"); !exists {
+        t.Errorf("Test failed, expected non-empty directory to be kept")
+    }
+}
+
+// Fails to open one file for shredding, while still allowing it to be
+// created
+type fsThatErrorsOpening struct {
+    afero.Fs
+    failing string
+}
+
+func (fs fsThatErrorsOpening) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    if name == fs.failing && flag&os.O_RDWR != 0 {
+        return nil, errors.New("Some awful open error")
+    }
+    return fs.Fs.OpenFile(name, flag, perm)
+}
+
+// ShredDir needs to lstat, so wrappers used with it pass that through
+func lstatThrough(fs afero.Fs, name string) (os.FileInfo, bool, error) {
+    return fs.(afero.Lstater).LstatIfPossible(name)
+}
+
+func (fs fsThatErrorsOpening) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+    return lstatThrough(fs.Fs, name)
+}
+
+func TestShredDirCarriesOnAfterFailures(t *testing.T) {
+    AppFs = fsThatErrorsOpening{Fs: afero.NewMemMapFs(), failing: "/tree/b.log"}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrOpen) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrOpen, err)
+    }
+    for path := range testTree {
+        exists, _ := afero.Exists(AppFs, path)
+        if shouldRemain := path == "/tree/b.log"; exists != shouldRemain {
+            t.Errorf("Test failed for %s, expected to exist: '%t', got:  '%t'", path, shouldRemain, exists)
+        }
+    }
+}
+
+func TestShredDirMissingRootReturnsError(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    err := ShredDir("/nonexistent", Options{})
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+}
+
+// Hides LstatIfPossible, so symlinks would look like what they point to
+type fsThatCannotLstat struct {
+    afero.Fs
+}
+
+func TestShredDirRejectsFilesystemThatCannotLstat(t *testing.T) {
+    memFs := afero.NewMemMapFs()
+    AppFs = fsThatCannotLstat{memFs}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrNoLstat) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrNoLstat, err)
+    }
+    for path, content := range testTree {
+        remaining, _ := afero.ReadFile(memFs, path)
+        if string(remaining) != content {
+            t.Errorf("Test failed for %s, expected it untouched, got:  '%s'", path, remaining)
+        }
+    }
+}
+
+// Tracks the most files open for writing at any one time
+type fsThatCountsConcurrentOpens struct {
+    afero.Fs
+    mu      sync.Mutex
+    open    int
+    maxOpen int
+}
+
+func (fs *fsThatCountsConcurrentOpens) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    file, err := fs.Fs.OpenFile(name, flag, perm)
+    if err != nil || flag&os.O_RDWR == 0 {
+        return file, err
+    }
+
+    fs.mu.Lock()
+    fs.open++
+    fs.maxOpen = max(fs.maxOpen, fs.open)
+    fs.mu.Unlock()
+
+    return &fileThatCountsCloses{File: file, fs: fs}, nil
+}
+
+func (fs *fsThatCountsConcurrentOpens) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+    return lstatThrough(fs.Fs, name)
+}
+
+type fileThatCountsCloses struct {
+    afero.File
+    fs *fsThatCountsConcurrentOpens
+}
+
+func (f *fileThatCountsCloses) Close() error {
+    // Hold the file open long enough for workers to overlap
+    time.Sleep(5 * time.Millisecond)
+
+    f.fs.mu.Lock()
+    f.fs.open--
+    f.fs.mu.Unlock()
+
+    return f.File.Close()
+}
+
+func TestShredDirLimitsConcurrency(t *testing.T) {
+    fs := &fsThatCountsConcurrentOpens{Fs: afero.NewMemMapFs()}
+    AppFs = fs
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Workers: 2})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if fs.maxOpen < 1 || fs.maxOpen > 2 {
+        t.Errorf("Test failed, expected between 1 and 2 files open at once, got:  '%d'", fs.maxOpen)
+    }
+}
+
+func TestShredFileWithRemoveDeletesFile(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/dir/test.txt", []byte("Some bytes that need replacing"), 0644)
+
+    // When
+    err := ShredFile("/dir/test.txt", Options{Remove: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    entries, _ := afero.ReadDir(AppFs, "/dir")
+    if len(entries) != 0 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 0, len(entries))
+    }
+}
+
+func TestShredDirPreScanStopsAtLimitsBeforeShredding(t *testing.T) {
+    cases := []struct {
+        name     string
+        opts     Options
+        expected error
+    }{
+        {"depth", Options{MaxDepth: 2}, ErrTooDeep},
+        {"entries", Options{MaxEntries: 5}, ErrTooManyEntries},
+    }
+
+    for _, c := range cases {
+        AppFs = afero.NewMemMapFs()
+
+        // Given
+        createTestTree(t)
+        c.opts.PreScan = true
+        c.opts.Remove = true
+
+        // When
+        err := ShredDir("/tree", c.opts)
+
+        // Then
+        if !errors.Is(err, c.expected) {
+            t.Errorf("Test failed for %s, expected: '%v', got:  '%v'", c.name, c.expected, err)
+        }
+        for path, content := range testTree {
+            remaining, _ := afero.ReadFile(AppFs, path)
+            if string(remaining) != content {
+                t.Errorf("Test failed for %s, expected %s untouched, got:  '%s'", c.name, path, remaining)
+            }
+        }
+    }
+
+    AppFs = afero.NewOsFs()
+}
+
+func TestShredDirStopsWalkingAtMaxEntries(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true, MaxEntries: 1})
+
+    // Then
+    if !errors.Is(err, ErrTooManyEntries) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrTooManyEntries, err)
+    }
+    if exists, _ := afero.Exists(AppFs, "/tree/sub/deeper/d.db"); !exists {
+        t.Errorf("Test failed, expected the rest of the tree to be kept")
+    }
+}
+
+func TestShredDirWithinLimitsShredsEverything(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true, PreScan: true, MaxDepth: 3, MaxEntries: 6})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if exists, _ := afero.Exists(AppFs, "/tree"); exists {
+        t.Errorf("Test failed, expected the whole tree to be removed")
+    }
+}
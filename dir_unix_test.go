@@ -0,0 +1,144 @@
+//go:build unix
+
+package shredder
+
+import (
+    "context"
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "shredder/faultfs"
+    "sort"
+    "testing"
+)
+
+// Hard-linked files are only recognised where link counts and inode
+// numbers are available, so these tests are unix-only
+
+func TestShredDirOverwritesHardLinkedFilesOnce(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewOsFs(), writes: &writes}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    dir := t.TempDir()
+    os.Mkdir(filepath.Join(dir, "sub"), 0755)
+    createLinkedFile(t, filepath.Join(dir, "a"), filepath.Join(dir, "sub", "b"))
+    os.WriteFile(filepath.Join(dir, "c"), []byte("Unlinked bytes that need replacing"), 0644)
+    writes = 0
+
+    // When
+    err := ShredDir(dir, Options{Passes: 1, Remove: true, Workers: 1})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if writes != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, writes)
+    }
+    if _, statErr := os.Stat(dir); !os.IsNotExist(statErr) {
+        t.Errorf("Test failed, expected the whole tree to be removed")
+    }
+}
+
+func TestShredDirKeepsLinkedNamesWithoutRemove(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewOsFs(), writes: &writes}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    dir := t.TempDir()
+    first, second := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+    createLinkedFile(t, first, second)
+    writes = 0
+
+    // When
+    err := ShredDir(dir, Options{Passes: 1})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if writes != 1 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 1, writes)
+    }
+    content, _ := os.ReadFile(second)
+    if string(content) == "Some bytes that need replacing" {
+        t.Errorf("Test failed, expected the shared contents to be overwritten")
+    }
+}
+
+func TestShredDirResultsListLinkedNames(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+    createLinkedFile(t, a, b)
+    var results []Result
+    record := ResultWriterFunc(func(ctx context.Context, result Result) error {
+        results = append(results, result)
+        return nil
+    })
+
+    // When
+    ShredDir(dir, Options{Results: record})
+
+    // Then
+    if len(results) != 1 {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", 1, len(results))
+    }
+    names := append([]string{results[0].Path}, results[0].Links...)
+    sort.Strings(names)
+    if len(names) != 2 || names[0] != a || names[1] != b {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", []string{a, b}, names)
+    }
+}
+
+func TestShredDirRejectsSymlinkedRoot(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    target := filepath.Join(dir, "target")
+    os.Mkdir(target, 0755)
+    os.WriteFile(filepath.Join(target, "a.txt"), []byte("Some bytes that need replacing"), 0644)
+    link := filepath.Join(dir, "link")
+    os.Symlink(target, link)
+
+    // When
+    err := ShredDir(link, Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrSymlinkRoot) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrSymlinkRoot, err)
+    }
+    content, _ := os.ReadFile(filepath.Join(target, "a.txt"))
+    if string(content) != "Some bytes that need replacing" {
+        t.Errorf("Test failed, expected the target to be untouched, got:  '%s'", content)
+    }
+}
+
+func TestShredDirThroughWrappingFsLeavesSymlinkTargetsAlone(t *testing.T) {
+    AppFs = faultfs.New(afero.NewOsFs(), faultfs.Faults{})
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    dir := t.TempDir()
+    outside := filepath.Join(dir, "outside.txt")
+    os.WriteFile(outside, []byte("Bytes that must survive"), 0644)
+    root := filepath.Join(dir, "root")
+    os.Mkdir(root, 0755)
+    os.Symlink(outside, filepath.Join(root, "file-link"))
+    os.Symlink(dir, filepath.Join(root, "dir-link"))
+
+    // When
+    err := ShredDir(root, Options{Remove: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := os.ReadFile(outside)
+    if string(content) != "Bytes that must survive" {
+        t.Errorf("Test failed, expected the symlink target to be untouched, got:  '%s'", content)
+    }
+}
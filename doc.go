@@ -0,0 +1,33 @@
+// Package shredder overwrites files in place before deleting them, so
+// their contents cannot be recovered from the disk afterwards.
+//
+// # Compatibility
+//
+// Within major version 1 the exported API only grows: functions,
+// types, fields and errors are never removed or changed in a way that
+// breaks code using them. This holds for faultfs too. api_test.go
+// enforces it by type-checking both packages and comparing their
+// exported API against testdata/api.txt, where a changed type, value or
+// signature, or a method added to an interface, shows as a changed line.
+// Additions are recorded with
+//
+//     go test -run TestAPI -update
+//
+// and a diff that removes or changes a line in that file is a breaking
+// change.
+//
+// Behaviour that is not part of the API, such as the exact text of
+// error messages, may change; compare errors with errors.Is against the
+// sentinels in errors.go instead. The panicking forms - Shred,
+// OverwriteStreamWithRandomBytes, GenerateRandomBytes and GetFileLength -
+// are kept for existing callers but receive no new options.
+//
+// A breaking change will be released under the module path
+// shredder/v2, in a v2 directory alongside this package, so both
+// versions can be imported side by side during an upgrade. Version 1
+// keeps receiving fixes while v2 is new. Planned for v2:
+//
+//   - remove the panicking forms
+//   - return results in a value rather than through Options out-parameters
+//   - replace the package-level configuration variables with Options fields
+package shredder
@@ -0,0 +1,69 @@
+package shredder
+
+import (
+    "context"
+    "os"
+    "os/signal"
+    "sync"
+    "sync/atomic"
+)
+
+var emergencyStopped atomic.Bool
+
+// EmergencyStop halts every overwrite in the process at its next chunk,
+// whichever goroutine is running it, and makes new ones fail with
+// ErrEmergencyStop until ClearEmergencyStop is called. Files caught
+// mid-pass are left partly overwritten and are never removed.
+// WipeMapped stops between passes, and RotateSecretFile and SecureMove
+// refuse to start, so neither changes anything once stopped.
+func EmergencyStop() {
+    emergencyStopped.Store(true)
+}
+
+// ClearEmergencyStop lets shredding start again after EmergencyStop.
+// Operations that were halted are not resumed.
+func ClearEmergencyStop() {
+    emergencyStopped.Store(false)
+}
+
+// EmergencyStopOnSignal calls EmergencyStop when the process receives
+// any of the given signals, such as syscall.SIGQUIT. The returned
+// function stops listening, and may be called more than once.
+func EmergencyStopOnSignal(signals ...os.Signal) func() {
+    received := make(chan os.Signal, 1)
+    done := make(chan struct{})
+    signal.Notify(received, signals...)
+
+    go func() {
+        for {
+            select {
+            case <-received:
+                EmergencyStop()
+            case <-done:
+                return
+            }
+        }
+    }()
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            signal.Stop(received)
+            close(done)
+        })
+    }
+}
+
+// interrupted returns why work should stop now, if it should: an
+// emergency stop, or the cause the context was cancelled with
+func interrupted(ctx context.Context) error {
+    if emergencyStopped.Load() {
+        return ErrEmergencyStop
+    }
+
+    if ctx.Err() == nil {
+        return nil
+    }
+
+    return context.Cause(ctx)
+}
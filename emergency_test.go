@@ -0,0 +1,106 @@
+package shredder
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "testing"
+)
+
+func TestEmergencyStopRefusesNewWork(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+    defer ClearEmergencyStop()
+
+    // Given
+    afero.WriteFile(AppFs, "/file", []byte("Some bytes that need replacing"), 0644)
+    EmergencyStop()
+
+    // When
+    err := ShredFile("/file", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrEmergencyStop) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrEmergencyStop, err)
+    }
+    content, _ := afero.ReadFile(AppFs, "/file")
+    if string(content) != "Some bytes that need replacing" {
+        t.Errorf("Test failed, expected the file to be untouched, got:  '%s'", content)
+    }
+}
+
+func TestEmergencyStopHaltsInFlightOverwrites(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+    defer ClearEmergencyStop()
+
+    // Given
+    afero.WriteFile(AppFs, "/file", make([]byte, 64), 0644)
+    chunks := 0
+    stopAfterFirstChunk := func(event ProgressEvent) {
+        chunks++
+        EmergencyStop()
+    }
+
+    // When
+    err := ShredFile("/file", Options{BufferSize: 8, Remove: true, Progress: stopAfterFirstChunk})
+
+    // Then
+    if !errors.Is(err, ErrEmergencyStop) || chunks != 1 {
+        t.Errorf("Test failed, expected: '%v' after 1 chunk, got:  '%v' after %d", ErrEmergencyStop, err, chunks)
+    }
+    if exists, _ := afero.Exists(AppFs, "/file"); !exists {
+        t.Errorf("Test failed, expected a halted file not to be removed")
+    }
+}
+
+func TestClearEmergencyStopAllowsWorkAgain(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    EmergencyStop()
+    ShredDir("/tree", Options{})
+
+    // When
+    ClearEmergencyStop()
+    err := ShredDir("/tree", Options{Remove: true})
+
+    // Then
+    if err != nil {
+        t.Errorf("Test failed, unexpected error: %v", err)
+    }
+    if exists, _ := afero.Exists(AppFs, "/tree"); exists {
+        t.Errorf("Test failed, expected the tree to be removed")
+    }
+}
+
+func TestEmergencyStopRefusesRotateAndMove(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+    defer ClearEmergencyStop()
+
+    // Given
+    afero.WriteFile(AppFs, "/secrets/token", []byte("old secret"), 0600)
+    EmergencyStop()
+
+    // When
+    rotateErr := RotateSecretFile("/secrets/token", []byte("new secret"))
+    moveErr := SecureMove("/secrets/token", "/moved/token")
+
+    // Then
+    if !errors.Is(rotateErr, ErrEmergencyStop) && !errors.Is(rotateErr, errors.ErrUnsupported) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrEmergencyStop, rotateErr)
+    }
+    if !errors.Is(moveErr, ErrEmergencyStop) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrEmergencyStop, moveErr)
+    }
+    entries, _ := afero.ReadDir(AppFs, "/secrets")
+    content, _ := afero.ReadFile(AppFs, "/secrets/token")
+    if len(entries) != 1 || string(content) != "old secret" {
+        t.Errorf("Test failed, expected only the untouched original, got:  '%d' entries, '%s'", len(entries), content)
+    }
+    if exists, _ := afero.Exists(AppFs, "/moved/token"); exists {
+        t.Errorf("Test failed, expected nothing to be copied")
+    }
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package shredder
+
+import (
+    "syscall"
+    "testing"
+    "time"
+)
+
+func TestEmergencyStopOnSignal(t *testing.T) {
+    defer ClearEmergencyStop()
+
+    // Given
+    stop := EmergencyStopOnSignal(syscall.SIGUSR1)
+    // Stopping listening twice must be harmless
+    defer stop()
+    defer stop()
+
+    // When
+    syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+    // Then
+    deadline := time.Now().Add(time.Second)
+    for !emergencyStopped.Load() && time.Now().Before(deadline) {
+        time.Sleep(time.Millisecond)
+    }
+    if !emergencyStopped.Load() {
+        t.Errorf("Test failed, expected the signal to trigger an emergency stop")
+    }
+}
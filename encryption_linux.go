@@ -0,0 +1,39 @@
+//go:build linux && (386 || amd64 || arm || arm64 || loong64 || riscv64 || s390x)
+
+package shredder
+
+import (
+    "os"
+    "syscall"
+    "unsafe"
+)
+
+const (
+    // _IOR('f', 1, long) in the asm-generic encoding, whose size field
+    // depends on the word size. Architectures that encode ioctl
+    // directions differently use encryption_other.go instead.
+    fsIocGetFlags = 0x80006601 | uintptr(unsafe.Sizeof(uintptr(0)))<<16
+    fsEncryptFl   = 0x00000800
+)
+
+// isFilesystemEncrypted checks the inode flags for FS_ENCRYPT_FL, which
+// fscrypt sets on every file in an encrypted directory. Files without a
+// descriptor, or on filesystems without inode flags, report false.
+func isFilesystemEncrypted(file any, info os.FileInfo) bool {
+    descriptor, ok := file.(interface {
+        Fd() uintptr
+    })
+    if !ok {
+        return false
+    }
+
+    // The kernel writes an int regardless of the ioctl's declared size
+    var flags int32
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, descriptor.Fd(),
+        fsIocGetFlags, uintptr(unsafe.Pointer(&flags)))
+    if errno != 0 {
+        return false
+    }
+
+    return flags&fsEncryptFl != 0
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !(linux && (386 || amd64 || arm || arm64 || loong64 || riscv64 || s390x))
+
+package shredder
+
+import "os"
+
+// isFilesystemEncrypted cannot detect per-file encryption on this platform
+func isFilesystemEncrypted(file any, info os.FileInfo) bool {
+    return false
+}
@@ -0,0 +1,19 @@
+package shredder
+
+import (
+    "os"
+    "syscall"
+)
+
+// Not defined by the syscall package
+const fileAttributeEncrypted = 0x4000
+
+// isFilesystemEncrypted checks for the EFS encrypted attribute
+func isFilesystemEncrypted(file any, info os.FileInfo) bool {
+    attributes, ok := info.Sys().(*syscall.Win32FileAttributeData)
+    if !ok {
+        return false
+    }
+
+    return attributes.FileAttributes&fileAttributeEncrypted != 0
+}
@@ -0,0 +1,48 @@
+package shredder
+
+import (
+    "errors"
+    "fmt"
+)
+
+// Errors returned by the error-returning API wrap one of these, so
+// callers can tell failures apart with errors.Is
+var (
+    ErrOpen             = errors.New("Error opening file")
+    ErrStat             = errors.New("Error getting file statistics")
+    ErrRead             = errors.New("Error reading file")
+    ErrWrite            = errors.New("Error writing random bytes to stream")
+    ErrSync             = errors.New("Error syncing writer")
+    ErrSeek             = errors.New("Error seeking writer")
+    ErrNotSeekable      = errors.New("Writer does not support seeking")
+    ErrAppendModeHandle = errors.New("Writer is in append mode and cannot overwrite in place")
+    ErrRandom           = errors.New("Error generating random bytes")
+    ErrRemove           = errors.New("Error removing file")
+    ErrNotSameFile      = errors.New("Names do not all refer to the same file")
+    ErrVerify           = errors.New("Error verifying overwrite")
+    ErrInvalidRange     = errors.New("Invalid range to overwrite")
+    ErrSymlinkRoot      = errors.New("Root is a symlink")
+    ErrNoLstat          = errors.New("Filesystem cannot tell symlinks from what they point to")
+    ErrProtected        = errors.New("Directory is marked as protected")
+    ErrTooDeep          = errors.New("Tree is deeper than MaxDepth")
+    ErrTooManyEntries   = errors.New("Tree has more entries than MaxEntries")
+    ErrFilesFailed      = errors.New("Error shredding files")
+    ErrEmergencyStop    = errors.New("Shredding halted by emergency stop")
+)
+
+// Causes to cancel an operation's context with, through
+// context.WithCancelCause. A cancelled operation returns the context's
+// cause rather than just context.Canceled, so automation can tell an
+// abort or a policy refusal, which should stay stopped, from a shutdown
+// or a deadline, which can be retried.
+var (
+    ErrAborted  = errors.New("Shredding aborted")
+    ErrRefused  = errors.New("Shredding refused by policy")
+    ErrShutdown = errors.New("Shredding stopped for shutdown")
+)
+
+// wrapError marks err as an instance of kind while keeping the
+// underlying cause available to errors.Is and errors.As
+func wrapError(kind error, err error) error {
+    return fmt.Errorf("%w: %w", kind, err)
+}
@@ -0,0 +1,168 @@
+package shredder
+
+import (
+    "context"
+    "fmt"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// Sustained throughput Estimate assumes, in bytes per second. The
+// defaults are conservative for spinning disks; set them from a
+// measurement of the target storage for useful durations.
+var EstimateWriteThroughput int64 = 100 << 20
+var EstimateReadThroughput int64 = 200 << 20
+
+// An Estimation describes the work a shred would do, without doing any
+type Estimation struct {
+    // Regular files that would be shredded, counting hard-linked names
+    // of the same file once, and the bytes overwritten in each pass
+    Files int
+    Bytes int64
+
+    // Passes per file. Files may get fewer when ReducePassesForHighEntropy
+    // is on, so this is the most any file gets.
+    Passes int
+
+    // Total I/O across every pass, including reading back for Verify
+    WriteBytes int64
+    ReadBytes  int64
+
+    // WriteBytes and ReadBytes at the assumed throughputs
+    Duration time.Duration
+}
+
+// Estimate reports what ShredDir, for a directory, or ShredFile, for
+// a regular file or device, would do to target with opts. Anything
+// else, including a symlink to a directory, is an error, as are a
+// directory on a filesystem that cannot lstat or marked as protected,
+// and removing a device. Protected directories within the tree are left
+// out, as ShredDir would leave them alone. Files are only opened for
+// reading: devices to find their size, directories to check them for
+// ProtectedXattr, and regular files to inspect them when
+// ReducePassesForHighEntropy or ReducePassesForEncryptedFilesystem is
+// on. Devices always get every pass.
+func Estimate(target string, opts Options) (Estimation, error) {
+    return estimate(context.Background(), target, opts)
+}
+
+// estimate is Estimate, stopping the walk if the context is cancelled
+func estimate(ctx context.Context, target string, opts Options) (Estimation, error) {
+    var estimation Estimation
+
+    addFile := func(path string, info os.FileInfo, size int64) error {
+        passes, err := filePasses(path, info, opts)
+        if err != nil {
+            return err
+        }
+
+        bytes := max(size-opts.Offset, 0)
+        estimation.Files++
+        estimation.Bytes += bytes
+        estimation.Passes = max(estimation.Passes, passes)
+        estimation.WriteBytes += bytes * int64(passes)
+
+        // Verify reads the original contents and then the final pass,
+        // or as much of them as the sampling chooses
+        if opts.Verify {
+            blocks := (bytes + int64(opts.bufferSize()) - 1) / int64(opts.bufferSize())
+            estimation.ReadBytes += int64(float64(2*bytes) * opts.Sampling.fraction(blocks))
+        }
+        return nil
+    }
+
+    info, err := AppFs.Stat(target)
+    if err != nil {
+        return Estimation{}, wrapError(ErrStat, err)
+    }
+
+    if info.Mode().IsRegular() {
+        err = addFile(target, info, info.Size())
+    } else if info.Mode()&os.ModeDevice != 0 && opts.Remove {
+        err = fmt.Errorf("%w: %s is a device", ErrRemove, target)
+    } else if info.Mode()&os.ModeDevice != 0 {
+        var size int64
+        size, err = deviceSize(target, info)
+        if err == nil {
+            err = addFile(target, info, size)
+        }
+    } else if !info.IsDir() {
+        err = fmt.Errorf("Error estimating %s: not a regular file, directory or device", target)
+    } else if err = checkRoot(target); err == nil {
+        err = checkProtected(target, opts)
+    }
+    if err == nil && info.IsDir() {
+        seen := make(map[fileID]bool)
+        limits := newWalkLimits(target, opts)
+        err = afero.Walk(AppFs, target, func(path string, info os.FileInfo, err error) error {
+            if err := interrupted(ctx); err != nil {
+                return err
+            }
+
+            if err := limits.check(path); err != nil {
+                return err
+            }
+
+            if err != nil {
+                return wrapError(ErrStat, err)
+            }
+
+            // ShredDir leaves protected directories alone, so count nothing
+            // in them
+            if info.IsDir() && path != target && checkProtected(path, opts) != nil {
+                return filepath.SkipDir
+            }
+
+            if !info.Mode().IsRegular() || (opts.Match != nil && !opts.Match.Match(path, info)) {
+                return nil
+            }
+
+            if id, ok := linkedFileID(info); ok {
+                if seen[id] {
+                    return nil
+                }
+                seen[id] = true
+            }
+
+            return addFile(path, info, info.Size())
+        })
+    }
+    if err != nil {
+        return Estimation{}, err
+    }
+
+    estimation.Duration = throughputDuration(estimation.WriteBytes, EstimateWriteThroughput) +
+        throughputDuration(estimation.ReadBytes, EstimateReadThroughput)
+
+    return estimation, nil
+}
+
+// filePasses is the number of passes overwriteFile would make
+func filePasses(path string, info os.FileInfo, opts Options) (int, error) {
+    if len(opts.Scheme.Passes) > 0 {
+        return len(opts.Scheme.Passes), nil
+    }
+
+    passes, _, err := passesFor(path, info, opts)
+    return passes, err
+}
+
+func throughputDuration(bytes int64, perSecond int64) time.Duration {
+    if perSecond <= 0 {
+        return 0
+    }
+
+    return time.Duration(float64(bytes) / float64(perSecond) * float64(time.Second))
+}
+
+func deviceSize(path string, info os.FileInfo) (int64, error) {
+    file, err := AppFs.Open(path)
+    if err != nil {
+        return 0, wrapError(ErrOpen, err)
+    }
+    defer file.Close()
+
+    return targetSize(file, info)
+}
@@ -0,0 +1,142 @@
+package shredder
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "testing"
+    "time"
+)
+
+func TestEstimateDirectory(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    var expectedBytes int64
+    for _, content := range testTree {
+        expectedBytes += int64(len(content))
+    }
+
+    // When
+    estimation, err := Estimate("/tree", Options{Scheme: SchemeDoD, Verify: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if estimation.Files != len(testTree) {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", len(testTree), estimation.Files)
+    }
+    if estimation.Bytes != expectedBytes {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", expectedBytes, estimation.Bytes)
+    }
+    if estimation.Passes != 3 || estimation.WriteBytes != 3*expectedBytes {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 3*expectedBytes, estimation.WriteBytes)
+    }
+    if estimation.ReadBytes != 2*expectedBytes {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2*expectedBytes, estimation.ReadBytes)
+    }
+}
+
+func TestEstimateDoesNotChangeFiles(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    Estimate("/tree", Options{Remove: true})
+
+    // Then
+    for path, expected := range testTree {
+        content, _ := afero.ReadFile(AppFs, path)
+        if string(content) != expected {
+            t.Errorf("Test failed, expected: '%s', got:  '%s'", expected, content)
+        }
+    }
+}
+
+func TestEstimateDurationUsesThroughput(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+    defer func(old int64) { EstimateWriteThroughput = old }(EstimateWriteThroughput)
+
+    // Given
+    afero.WriteFile(AppFs, "/file", make([]byte, 1000), 0644)
+    EstimateWriteThroughput = 1000
+
+    // When
+    estimation, _ := Estimate("/file", Options{Passes: 2})
+
+    // Then
+    if estimation.Duration != 2*time.Second {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", 2*time.Second, estimation.Duration)
+    }
+}
+
+func TestEstimateMissingTargetReturnsError(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    _, err := Estimate("/missing", Options{})
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+}
+
+func TestEstimateRejectsFilesystemThatCannotLstat(t *testing.T) {
+    AppFs = fsThatCannotLstat{afero.NewMemMapFs()}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    _, err := Estimate("/tree", Options{})
+
+    // Then
+    if !errors.Is(err, ErrNoLstat) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrNoLstat, err)
+    }
+}
+
+func TestEstimateStopsAtMaxDepth(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    _, err := Estimate("/tree", Options{MaxDepth: 2})
+
+    // Then
+    if !errors.Is(err, ErrTooDeep) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrTooDeep, err)
+    }
+}
+
+func TestEstimateReadsOnlySampledBlocks(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.bin", make([]byte, 64), 0644)
+    sampling := Sampling{Strategy: SampleEveryNth, N: 4}
+
+    // When
+    estimation, err := Estimate("test.bin", Options{Verify: true, BufferSize: 8, Sampling: sampling})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if estimation.ReadBytes != 2*16 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2*16, estimation.ReadBytes)
+    }
+}
@@ -0,0 +1,84 @@
+//go:build unix
+
+package shredder
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+)
+
+func TestEstimateRejectsSpecialFiles(t *testing.T) {
+    // Given
+    fifo := filepath.Join(t.TempDir(), "fifo")
+    if err := syscall.Mkfifo(fifo, 0600); err != nil {
+        t.Skipf("Could not create a fifo: %v", err)
+    }
+
+    // When
+    estimation, err := Estimate(fifo, Options{})
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error, got:  '%+v'", estimation)
+    }
+}
+
+func TestEstimateSizesDevicesBySeeking(t *testing.T) {
+    // When
+    estimation, err := Estimate("/dev/null", Options{Passes: 1})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if estimation.Files != 1 || estimation.Bytes != 0 {
+        t.Errorf("Test failed, expected one empty device, got:  '%+v'", estimation)
+    }
+}
+
+func TestEstimateRejectsSymlinkedDirectory(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    target := filepath.Join(dir, "target")
+    os.Mkdir(target, 0755)
+    os.WriteFile(filepath.Join(target, "a.txt"), []byte("Some bytes that need replacing"), 0644)
+    link := filepath.Join(dir, "link")
+    os.Symlink(target, link)
+
+    // When
+    _, err := Estimate(link, Options{})
+
+    // Then
+    if !errors.Is(err, ErrSymlinkRoot) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrSymlinkRoot, err)
+    }
+}
+
+func TestEstimateGivesDevicesEveryPass(t *testing.T) {
+    ReducePassesForHighEntropy, ReducePassesForEncryptedFilesystem = true, true
+    defer func() { ReducePassesForHighEntropy, ReducePassesForEncryptedFilesystem = false, false }()
+
+    // When
+    estimation, err := Estimate("/dev/null", Options{Passes: 3})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if estimation.Passes != 3 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 3, estimation.Passes)
+    }
+}
+
+func TestEstimateRejectsRemovingDevices(t *testing.T) {
+    // When
+    _, err := Estimate("/dev/null", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrRemove) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrRemove, err)
+    }
+}
@@ -1,7 +1,13 @@
 package main
 
-import "shredder"
+import (
+    "log"
+    "shredder"
+)
 
 func main() {
-    shredder.Shred("abc")
+    err := shredder.ShredFile("abc", shredder.Options{})
+    if err != nil {
+        log.Fatal(err)
+    }
 }
@@ -0,0 +1,81 @@
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "github.com/spf13/afero"
+    "os"
+)
+
+func ExampleShred() {
+    // Work against an in-memory filesystem so the example is repeatable
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    secret := []byte("correct horse battery staple")
+    afero.WriteFile(AppFs, "secret.txt", secret, 0644)
+
+    Shred("secret.txt")
+
+    shredded, _ := afero.ReadFile(AppFs, "secret.txt")
+    fmt.Println(len(shredded) == len(secret), bytes.Equal(shredded, secret))
+    // Output: true false
+}
+
+func ExampleOverwriteStreamWithRandomBytes() {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    afero.WriteFile(AppFs, "stream.bin", make([]byte, 16), 0644)
+    file, _ := AppFs.OpenFile("stream.bin", os.O_RDWR, 0644)
+    defer file.Close()
+
+    // Any writer that can seek back to the start can be overwritten
+    OverwriteStreamWithRandomBytes(file, 16)
+
+    overwritten, _ := afero.ReadFile(AppFs, "stream.bin")
+    fmt.Println(len(overwritten))
+    // Output: 16
+}
+
+func ExampleShredFile() {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    err := ShredFile("missing.txt", Options{Passes: 1})
+
+    // Failures can be told apart without recovering from a panic
+    fmt.Println(errors.Is(err, ErrOpen))
+    // Output: true
+}
+
+func ExampleShredFile_scheme() {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    afero.WriteFile(AppFs, "secret.txt", []byte("top secret"), 0644)
+
+    // DoD 5220.22-M followed by zeros, so the file reads back blank
+    err := ShredFile("secret.txt", Options{Scheme: SchemeDoD.WithZeroPass()})
+
+    shredded, _ := afero.ReadFile(AppFs, "secret.txt")
+    fmt.Println(err, shredded)
+    // Output: <nil> [0 0 0 0 0 0 0 0 0 0]
+}
+
+func ExampleShredDir() {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    afero.WriteFile(AppFs, "/exports/2023.csv", []byte("name,email"), 0644)
+    afero.WriteFile(AppFs, "/exports/keep/README", []byte("keep me"), 0644)
+
+    // Shred and remove the CSV exports, leaving everything else alone
+    err := ShredDir("/exports", Options{Remove: true, Match: MustNameGlob("*.csv")})
+
+    csvExists, _ := afero.Exists(AppFs, "/exports/2023.csv")
+    readmeExists, _ := afero.Exists(AppFs, "/exports/keep/README")
+    fmt.Println(err, csvExists, readmeExists)
+    // Output: <nil> false true
+}
@@ -0,0 +1,205 @@
+// Package faultfs wraps an afero.Fs to inject I/O failures, so code that
+// writes through afero - the shredder package included - can have its
+// error paths tested systematically rather than with one-off mocks.
+//
+//     fs := faultfs.New(afero.NewMemMapFs(), faultfs.Faults{FailWrite: 3})
+//     shredder.AppFs = fs
+//
+// Write and sync faults are counted across every file opened through
+// the Fs, in call order.
+package faultfs
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "io"
+    "os"
+    "sync"
+    "syscall"
+    "time"
+)
+
+// ErrInjected is returned by injected failures that don't name their own
+// error
+var ErrInjected = errors.New("faultfs: injected fault")
+
+// Faults describes what to break. The zero value breaks nothing. Write
+// and sync calls are numbered from 1.
+type Faults struct {
+    // Fail this write call, writing nothing, with WriteErr
+    FailWrite int
+    WriteErr  error
+
+    // Fail this write call, writing nothing, with EINTR
+    InterruptWrite int
+
+    // Store at most this many bytes per write call, and report the
+    // shorter count without an error, as a misbehaving writer might
+    MaxWrite int
+
+    // Writes reaching past this many bytes into a file store what fits
+    // and fail with ENOSPC, as on a full disk. Zero means no limit.
+    DiskSize int64
+
+    // Fail this sync call with SyncErr
+    FailSync int
+    SyncErr  error
+
+    // Sleep this long before every sync
+    SyncDelay time.Duration
+}
+
+// Fs is an afero.Fs whose files misbehave as its Faults describe
+type Fs struct {
+    afero.Fs
+    faults Faults
+
+    mu     sync.Mutex
+    writes int
+    syncs  int
+}
+
+func New(base afero.Fs, faults Faults) *Fs {
+    return &Fs{Fs: base, faults: faults}
+}
+
+// Writes returns how many write calls have been made through the Fs
+func (fs *Fs) Writes() int {
+    fs.mu.Lock()
+    defer fs.mu.Unlock()
+    return fs.writes
+}
+
+// Syncs returns how many sync calls have been made through the Fs
+func (fs *Fs) Syncs() int {
+    fs.mu.Lock()
+    defer fs.mu.Unlock()
+    return fs.syncs
+}
+
+func (fs *Fs) Create(name string) (afero.File, error) {
+    return fs.wrap(fs.Fs.Create(name))
+}
+
+func (fs *Fs) Open(name string) (afero.File, error) {
+    return fs.wrap(fs.Fs.Open(name))
+}
+
+func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    return fs.wrap(fs.Fs.OpenFile(name, flag, perm))
+}
+
+// LstatIfPossible passes through to the wrapped Fs, so code walking a
+// tree through faultfs still sees symlinks as symlinks. If the wrapped
+// Fs cannot lstat, it falls back to Stat and reports false, as afero's
+// own wrappers do.
+func (fs *Fs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+    if lstater, ok := fs.Fs.(afero.Lstater); ok {
+        return lstater.LstatIfPossible(name)
+    }
+
+    info, err := fs.Fs.Stat(name)
+    return info, false, err
+}
+
+func (fs *Fs) Name() string {
+    return "faultfs(" + fs.Fs.Name() + ")"
+}
+
+func (fs *Fs) wrap(file afero.File, err error) (afero.File, error) {
+    if err != nil {
+        return nil, err
+    }
+
+    return &File{File: file, fs: fs}, nil
+}
+
+func (fs *Fs) nextWrite() int {
+    fs.mu.Lock()
+    defer fs.mu.Unlock()
+    fs.writes++
+    return fs.writes
+}
+
+func (fs *Fs) nextSync() int {
+    fs.mu.Lock()
+    defer fs.mu.Unlock()
+    fs.syncs++
+    return fs.syncs
+}
+
+// File is an afero.File opened through a faultfs Fs
+type File struct {
+    afero.File
+    fs *Fs
+}
+
+func (f *File) Write(p []byte) (int, error) {
+    offset, err := f.File.Seek(0, io.SeekCurrent)
+    if err != nil {
+        return 0, err
+    }
+
+    return f.write(p, offset, f.File.Write)
+}
+
+func (f *File) WriteAt(p []byte, offset int64) (int, error) {
+    return f.write(p, offset, func(p []byte) (int, error) {
+        return f.File.WriteAt(p, offset)
+    })
+}
+
+func (f *File) WriteString(s string) (int, error) {
+    return f.Write([]byte(s))
+}
+
+func (f *File) write(p []byte, offset int64, write func([]byte) (int, error)) (int, error) {
+    faults := f.fs.faults
+    call := f.fs.nextWrite()
+
+    if call == faults.FailWrite {
+        return 0, orInjected(faults.WriteErr)
+    }
+
+    if call == faults.InterruptWrite {
+        return 0, &os.PathError{Op: "write", Path: f.Name(), Err: syscall.EINTR}
+    }
+
+    var fault error
+    if faults.DiskSize > 0 && offset+int64(len(p)) > faults.DiskSize {
+        p = p[:max(faults.DiskSize-offset, 0)]
+        fault = &os.PathError{Op: "write", Path: f.Name(), Err: syscall.ENOSPC}
+    }
+
+    if faults.MaxWrite > 0 && len(p) > faults.MaxWrite {
+        p = p[:faults.MaxWrite]
+    }
+
+    n, err := write(p)
+    if err != nil {
+        return n, err
+    }
+
+    return n, fault
+}
+
+func (f *File) Sync() error {
+    faults := f.fs.faults
+    call := f.fs.nextSync()
+
+    time.Sleep(faults.SyncDelay)
+
+    if call == faults.FailSync {
+        return orInjected(faults.SyncErr)
+    }
+
+    return f.File.Sync()
+}
+
+func orInjected(err error) error {
+    if err == nil {
+        return ErrInjected
+    }
+
+    return err
+}
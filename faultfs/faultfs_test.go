@@ -0,0 +1,208 @@
+package faultfs
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+    "time"
+)
+
+func openTestFile(t *testing.T, faults Faults) (*Fs, afero.File) {
+    fs := New(afero.NewMemMapFs(), faults)
+
+    file, err := fs.Create("test.bin")
+    if err != nil {
+        t.Fatalf("Could not create test file: %v", err)
+    }
+
+    return fs, file
+}
+
+func TestFailsTheNthWrite(t *testing.T) {
+    // Given
+    _, file := openTestFile(t, Faults{FailWrite: 2})
+
+    // When
+    _, first := file.Write([]byte("one"))
+    n, second := file.Write([]byte("two"))
+    _, third := file.Write([]byte("three"))
+
+    // Then
+    if first != nil || third != nil {
+        t.Errorf("Test failed, expected only the second write to fail, got:  '%v' and '%v'", first, third)
+    }
+    if !errors.Is(second, ErrInjected) || n != 0 {
+        t.Errorf("Test failed, expected: '%v', got:  '%v' after '%d' bytes", ErrInjected, second, n)
+    }
+}
+
+func TestUsesGivenWriteError(t *testing.T) {
+    // Given
+    custom := errors.New("Some awful write error")
+    _, file := openTestFile(t, Faults{FailWrite: 1, WriteErr: custom})
+
+    // When
+    _, err := file.Write([]byte("one"))
+
+    // Then
+    if err != custom {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", custom, err)
+    }
+}
+
+func TestInterruptsTheNthWrite(t *testing.T) {
+    // Given
+    _, file := openTestFile(t, Faults{InterruptWrite: 1})
+
+    // When
+    _, err := file.Write([]byte("one"))
+
+    // Then
+    if !errors.Is(err, syscall.EINTR) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", syscall.EINTR, err)
+    }
+}
+
+func TestShortWritesReportFewerBytes(t *testing.T) {
+    // Given
+    _, file := openTestFile(t, Faults{MaxWrite: 4})
+
+    // When
+    n, err := file.Write([]byte("abcdefgh"))
+
+    // Then
+    if n != 4 || err != nil {
+        t.Errorf("Test failed, expected: '4, <nil>', got:  '%d, %v'", n, err)
+    }
+    stat, _ := file.Stat()
+    if stat.Size() != 4 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 4, stat.Size())
+    }
+}
+
+func TestRunsOutOfSpaceAtDiskSize(t *testing.T) {
+    // Given
+    _, file := openTestFile(t, Faults{DiskSize: 10})
+
+    // When
+    _, first := file.Write([]byte("abcdef"))
+    n, second := file.Write([]byte("ghijkl"))
+
+    // Then
+    if first != nil {
+        t.Errorf("Test failed, unexpected error: %v", first)
+    }
+    if !errors.Is(second, syscall.ENOSPC) || n != 4 {
+        t.Errorf("Test failed, expected: '4, %v', got:  '%d, %v'", syscall.ENOSPC, n, second)
+    }
+}
+
+func TestRunsOutOfSpaceForWriteAt(t *testing.T) {
+    // Given
+    _, file := openTestFile(t, Faults{DiskSize: 10})
+
+    // When
+    n, err := file.WriteAt([]byte("abcdef"), 8)
+
+    // Then
+    if !errors.Is(err, syscall.ENOSPC) || n != 2 {
+        t.Errorf("Test failed, expected: '2, %v', got:  '%d, %v'", syscall.ENOSPC, n, err)
+    }
+}
+
+func TestFailsTheNthSync(t *testing.T) {
+    // Given
+    fs, file := openTestFile(t, Faults{FailSync: 2})
+
+    // When
+    first := file.Sync()
+    second := file.Sync()
+
+    // Then
+    if first != nil || !errors.Is(second, ErrInjected) {
+        t.Errorf("Test failed, expected: '<nil>, %v', got:  '%v, %v'", ErrInjected, first, second)
+    }
+    if fs.Syncs() != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, fs.Syncs())
+    }
+}
+
+func TestDelaysSyncs(t *testing.T) {
+    // Given
+    _, file := openTestFile(t, Faults{SyncDelay: 10 * time.Millisecond})
+
+    // When
+    start := time.Now()
+    file.Sync()
+
+    // Then
+    if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+        t.Errorf("Test failed, expected at least 10ms, got:  '%v'", elapsed)
+    }
+}
+
+func TestCountsWritesAcrossFiles(t *testing.T) {
+    // Given
+    fs, first := openTestFile(t, Faults{FailWrite: 2})
+    second, _ := fs.Create("other.bin")
+
+    // When
+    first.Write([]byte("one"))
+    _, err := second.Write([]byte("two"))
+
+    // Then
+    if !errors.Is(err, ErrInjected) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrInjected, err)
+    }
+    if fs.Writes() != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, fs.Writes())
+    }
+}
+
+func TestLstatsThroughTheWrappedFs(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    link := filepath.Join(dir, "link")
+    err := os.Symlink(filepath.Join(dir, "target"), link)
+    if err != nil {
+        t.Skipf("Could not create symlink: %v", err)
+    }
+    fs := New(afero.NewOsFs(), Faults{})
+
+    // When
+    info, lstated, err := fs.LstatIfPossible(link)
+
+    // Then
+    if err != nil || !lstated {
+        t.Fatalf("Test failed, expected an lstat, got:  '%v', '%v'", lstated, err)
+    }
+    if info.Mode()&os.ModeSymlink == 0 {
+        t.Errorf("Test failed, expected a symlink, got:  '%v'", info.Mode())
+    }
+}
+
+// Hides everything but the afero.Fs methods of the Fs it wraps
+type fsThatCannotLstat struct {
+    afero.Fs
+}
+
+func TestFallsBackToStatWhenTheWrappedFsCannotLstat(t *testing.T) {
+    // Given
+    base := afero.NewMemMapFs()
+    afero.WriteFile(base, "test.bin", []byte("Some bytes"), 0644)
+    fs := New(fsThatCannotLstat{base}, Faults{})
+
+    // When
+    info, lstated, err := fs.LstatIfPossible("test.bin")
+
+    // Then
+    if err != nil || lstated {
+        t.Fatalf("Test failed, expected a plain stat, got:  '%v', '%v'", lstated, err)
+    }
+    if info.Size() != 10 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 10, info.Size())
+    }
+}
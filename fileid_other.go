@@ -0,0 +1,12 @@
+//go:build !unix
+
+package shredder
+
+import "os"
+
+type fileID struct{}
+
+// linkedFileID cannot identify files by inode on this platform
+func linkedFileID(info os.FileInfo) (fileID, bool) {
+    return fileID{}, false
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package shredder
+
+import (
+    "os"
+    "syscall"
+)
+
+// fileID identifies a file independently of its name
+type fileID struct {
+    device uint64
+    inode  uint64
+}
+
+// linkedFileID returns the identity of a file with more than one hard
+// link. Files with a single name need no deduplication.
+func linkedFileID(info os.FileInfo) (fileID, bool) {
+    stat, ok := info.Sys().(*syscall.Stat_t)
+    if !ok || stat.Nlink < 2 {
+        return fileID{}, false
+    }
+
+    return fileID{device: uint64(stat.Dev), inode: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package shredder
+
+import "errors"
+
+// DisableCoreDumps is only supported on unix platforms
+func DisableCoreDumps() (func() error, error) {
+    return nil, errors.ErrUnsupported
+}
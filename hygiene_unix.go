@@ -0,0 +1,37 @@
+//go:build unix
+
+package shredder
+
+import (
+    "fmt"
+    "syscall"
+)
+
+// DisableCoreDumps sets the soft core file size limit to zero, so a
+// crash mid-shred cannot write the data being destroyed to a core file.
+// The returned function restores the previous limit, so the process can
+// be hardened just while shredding:
+//
+//     restore, err := shredder.DisableCoreDumps()
+//     if err == nil {
+//         defer restore()
+//     }
+func DisableCoreDumps() (func() error, error) {
+    var previous syscall.Rlimit
+    err := syscall.Getrlimit(syscall.RLIMIT_CORE, &previous)
+    if err != nil {
+        return nil, fmt.Errorf("Error reading core dump limit: %w", err)
+    }
+
+    disabled := syscall.Rlimit{Cur: 0, Max: previous.Max}
+    err = syscall.Setrlimit(syscall.RLIMIT_CORE, &disabled)
+    if err != nil {
+        return nil, fmt.Errorf("Error disabling core dumps: %w", err)
+    }
+
+    restore := func() error {
+        return syscall.Setrlimit(syscall.RLIMIT_CORE, &previous)
+    }
+
+    return restore, nil
+}
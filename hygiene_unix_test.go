@@ -0,0 +1,40 @@
+//go:build unix
+
+package shredder
+
+import (
+    "syscall"
+    "testing"
+)
+
+func TestDisableCoreDumpsAndRestore(t *testing.T) {
+    // Given
+    var before syscall.Rlimit
+    syscall.Getrlimit(syscall.RLIMIT_CORE, &before)
+
+    // When
+    restore, err := DisableCoreDumps()
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    var during syscall.Rlimit
+    syscall.Getrlimit(syscall.RLIMIT_CORE, &during)
+    if during.Cur != 0 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 0, during.Cur)
+    }
+
+    // When
+    err = restore()
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    var after syscall.Rlimit
+    syscall.Getrlimit(syscall.RLIMIT_CORE, &after)
+    if after != before {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", before, after)
+    }
+}
@@ -0,0 +1,119 @@
+package shredder
+
+import (
+    "fmt"
+    "io"
+    "math"
+)
+
+// Files whose sampled contents reach this many bits of entropy per byte
+// are reported as looking like ciphertext or compressed data
+var HighEntropyThreshold = 7.9
+
+// How much of a file Inspect reads when estimating entropy
+var InspectSampleSize int64 = 1 << 20
+
+// Below this many sampled bytes the entropy estimate is too noisy to
+// call a file high-entropy, however random it is
+const minEntropySample = 4096
+
+// Replaced in tests, which cannot create filesystem-encrypted files
+var filesystemEncrypted = isFilesystemEncrypted
+
+// An Inspection is a read-only description of a shred target
+type Inspection struct {
+    Size int64
+
+    // Shannon entropy of the sampled bytes, in bits per byte (0 to 8)
+    Entropy     float64
+    SampleSize  int64
+    HighEntropy bool
+
+    // Sparse and HardLinks are only known on platforms which expose
+    // allocation and link counts; HardLinks is 0 when unknown
+    Sparse    bool
+    HardLinks uint64
+
+    // Whether the file sits under filesystem-level encryption (fscrypt
+    // on Linux, EFS on Windows). Its blocks on disk are already
+    // ciphertext, so destroying the key may serve better than overwriting.
+    FilesystemEncrypted bool
+
+    // Human-readable notes about why overwriting may not reach all
+    // copies of the data
+    Caveats []string
+}
+
+// Inspect describes the file at path without opening it for writing, so
+// callers can decide whether multi-pass overwriting is worthwhile
+func Inspect(path string) (Inspection, error) {
+    file, err := AppFs.Open(path)
+    if err != nil {
+        return Inspection{}, wrapError(ErrOpen, err)
+    }
+    defer file.Close()
+
+    info, err := file.Stat()
+    if err != nil {
+        return Inspection{}, wrapError(ErrStat, err)
+    }
+    if !info.Mode().IsRegular() {
+        return Inspection{}, fmt.Errorf("Error inspecting %s: not a regular file", path)
+    }
+
+    inspection := Inspection{Size: info.Size()}
+
+    sample := make([]byte, min(info.Size(), InspectSampleSize))
+    n, err := io.ReadFull(file, sample)
+    if err != nil && err != io.ErrUnexpectedEOF {
+        return Inspection{}, wrapError(ErrRead, err)
+    }
+
+    inspection.SampleSize = int64(n)
+    inspection.Entropy = ShannonEntropy(sample[:n])
+    inspection.HighEntropy = n >= minEntropySample &&
+        inspection.Entropy >= HighEntropyThreshold
+
+    inspection.Sparse, inspection.HardLinks = fileLayout(info)
+    inspection.FilesystemEncrypted = filesystemEncrypted(file, info)
+
+    if inspection.HardLinks > 1 {
+        inspection.Caveats = append(inspection.Caveats, fmt.Sprintf(
+            "file has %d hard links; removing one name leaves the data reachable through the others",
+            inspection.HardLinks))
+    }
+    if inspection.Sparse {
+        inspection.Caveats = append(inspection.Caveats,
+            "file is sparse; overwriting will allocate blocks for its holes")
+    }
+    if inspection.FilesystemEncrypted {
+        inspection.Caveats = append(inspection.Caveats,
+            "file is encrypted by the filesystem; destroying its key removes the data without overwriting")
+    }
+
+    return inspection, nil
+}
+
+// ShannonEntropy returns the entropy of data in bits per byte
+func ShannonEntropy(data []byte) float64 {
+    if len(data) == 0 {
+        return 0
+    }
+
+    var counts [256]int
+    for _, b := range data {
+        counts[b]++
+    }
+
+    entropy := 0.0
+    total := float64(len(data))
+    for _, count := range counts {
+        if count == 0 {
+            continue
+        }
+        p := float64(count) / total
+        entropy -= p * math.Log2(p)
+    }
+
+    return entropy
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package shredder
+
+import "os"
+
+func fileLayout(info os.FileInfo) (sparse bool, hardLinks uint64) {
+    return false, 0
+}
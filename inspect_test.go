@@ -0,0 +1,179 @@
+package shredder
+
+import (
+    "bytes"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestInspectDetectsHighEntropyContent(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "cipher.bin", GenerateRandomBytes(64*1024), 0644)
+
+    // When
+    inspection, err := Inspect("cipher.bin")
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if !inspection.HighEntropy {
+        t.Errorf("Test failed, expected high entropy, got:  '%f'", inspection.Entropy)
+    }
+    if inspection.Size != 64*1024 || inspection.SampleSize != 64*1024 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d' (sampled '%d')",
+            64*1024, inspection.Size, inspection.SampleSize)
+    }
+}
+
+func TestInspectDoesNotFlagPlainText(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    text := bytes.Repeat([]byte("Some bytes that need replacing. "), 1024)
+    afero.WriteFile(AppFs, "plain.txt", text, 0644)
+
+    // When
+    inspection, err := Inspect("plain.txt")
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if inspection.HighEntropy {
+        t.Errorf("Test failed, expected low entropy, got:  '%f'", inspection.Entropy)
+    }
+}
+
+func TestInspectNeedsEnoughDataToFlagHighEntropy(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "short.bin", GenerateRandomBytes(256), 0644)
+
+    // When
+    inspection, _ := Inspect("short.bin")
+
+    // Then
+    if inspection.HighEntropy {
+        t.Errorf("Test failed, expected a short sample not to be flagged")
+    }
+}
+
+func TestInspectSamplesOnlyTheConfiguredAmount(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    originalSampleSize := InspectSampleSize
+    InspectSampleSize = 8192
+    defer func() {
+        AppFs = afero.NewOsFs()
+        InspectSampleSize = originalSampleSize
+    }()
+
+    // Given
+    afero.WriteFile(AppFs, "big.bin", GenerateRandomBytes(32*1024), 0644)
+
+    // When
+    inspection, _ := Inspect("big.bin")
+
+    // Then
+    if inspection.SampleSize != 8192 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 8192, inspection.SampleSize)
+    }
+}
+
+func TestInspectMissingFileReturnsError(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    _, err := Inspect("nonexistent_file.txt")
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+}
+
+func TestInspectDirectoryReturnsError(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    AppFs.Mkdir("dir", 0755)
+
+    // When
+    _, err := Inspect("dir")
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+}
+
+func TestInspectReportsHardLinks(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    original := filepath.Join(dir, "original")
+    os.WriteFile(original, []byte("Some bytes that need replacing"), 0644)
+    if err := os.Link(original, filepath.Join(dir, "alias")); err != nil {
+        t.Skipf("Hard links not supported here: %v", err)
+    }
+
+    // When
+    inspection, err := Inspect(original)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    info, _ := os.Stat(original)
+    if _, links := fileLayout(info); links == 0 {
+        t.Skip("Link counts not available on this platform")
+    }
+    if inspection.HardLinks != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, inspection.HardLinks)
+    }
+    if len(inspection.Caveats) == 0 {
+        t.Errorf("Test failed, expected a hard link caveat")
+    }
+}
+
+func TestInspectReportsUnencryptedFiles(t *testing.T) {
+    // Given
+    path := filepath.Join(t.TempDir(), "plain.txt")
+    os.WriteFile(path, []byte("Some bytes that need replacing"), 0644)
+
+    // When
+    inspection, err := Inspect(path)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if inspection.FilesystemEncrypted {
+        t.Errorf("Test failed, expected a temp file not to be filesystem-encrypted")
+    }
+}
+
+func TestInspectInMemoryFilesAreNotFilesystemEncrypted(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "plain.txt", []byte("Some bytes that need replacing"), 0644)
+
+    // When
+    inspection, _ := Inspect("plain.txt")
+
+    // Then
+    if inspection.FilesystemEncrypted {
+        t.Errorf("Test failed, expected in-memory files not to be filesystem-encrypted")
+    }
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package shredder
+
+import (
+    "os"
+    "syscall"
+)
+
+// fileLayout reports whether a file has unallocated holes and how many
+// names link to it, using the unix stat block and link counts
+func fileLayout(info os.FileInfo) (sparse bool, hardLinks uint64) {
+    stat, ok := info.Sys().(*syscall.Stat_t)
+    if !ok {
+        return false, 0
+    }
+
+    // st_blocks is always counted in 512-byte units
+    allocated := int64(stat.Blocks) * 512
+
+    return allocated < info.Size(), uint64(stat.Nlink)
+}
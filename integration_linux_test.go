@@ -0,0 +1,106 @@
+//go:build integration && linux
+
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "syscall"
+    "testing"
+)
+
+func TestIntegrationShredFileKeepsExtendedAttributesUntilRemoved(t *testing.T) {
+    // Given
+    path := filepath.Join(t.TempDir(), "labelled.txt")
+    writeRealFile(t, path, []byte("Some bytes that need replacing"))
+    if err := syscall.Setxattr(path, "user.shredder_test", []byte("label"), 0); err != nil {
+        t.Skipf("Extended attributes not supported here: %v", err)
+    }
+
+    // When
+    err := ShredFile(path, Options{Passes: 1})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    // Only the contents are overwritten; attributes go with the inode
+    value := make([]byte, 16)
+    n, err := syscall.Getxattr(path, "user.shredder_test", value)
+    if err != nil || string(value[:n]) != "label" {
+        t.Errorf("Test failed, expected: '%s', got:  '%s' (%v)", "label", value[:n], err)
+    }
+
+    // When
+    err = ShredFile(path, Options{Passes: 1, Remove: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+        t.Errorf("Test failed, expected the file and its attributes to be gone")
+    }
+}
+
+// Needs root to attach a loop device; run with sudo to include it
+func TestIntegrationShredFileOverwritesLoopDevice(t *testing.T) {
+    if os.Geteuid() != 0 {
+        t.Skip("Attaching a loop device needs root")
+    }
+
+    // Given
+    backing := filepath.Join(t.TempDir(), "disk.img")
+    original := bytes.Repeat([]byte("Some bytes that need replacing\n"), 1<<15)
+    writeRealFile(t, backing, original)
+
+    out, err := exec.Command("losetup", "--find", "--show", backing).Output()
+    if err != nil {
+        t.Skipf("Could not attach a loop device: %v", err)
+    }
+    device := strings.TrimSpace(string(out))
+    defer exec.Command("losetup", "--detach", device).Run()
+
+    estimation, err := Estimate(device, Options{Passes: 1})
+    if err != nil || estimation.Bytes != int64(len(original)) {
+        t.Errorf("Test failed, expected: '%d' bytes, got:  '%d' (%v)", len(original), estimation.Bytes, err)
+    }
+
+    // When
+    err = ShredFile(device, Options{Passes: 1, Verify: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := os.ReadFile(backing)
+    if len(content) != len(original) || bytes.Contains(content, []byte("Some bytes")) {
+        t.Errorf("Test failed, expected the whole backing file to be overwritten")
+    }
+}
+
+func TestIntegrationShredDirLeavesDirectoriesWithProtectedXattrAlone(t *testing.T) {
+    // Given
+    root := filepath.Join(t.TempDir(), "tree")
+    path := filepath.Join(root, "kept", "a.txt")
+    writeRealFile(t, path, []byte("Some bytes that must survive"))
+    if err := syscall.Setxattr(filepath.Dir(path), ProtectedXattr, []byte("1"), 0); err != nil {
+        t.Skipf("Extended attributes not supported here: %v", err)
+    }
+
+    // When
+    err := ShredDir(root, Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrProtected) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrProtected, err)
+    }
+    content, _ := os.ReadFile(path)
+    if string(content) != "Some bytes that must survive" {
+        t.Errorf("Test failed, expected the file untouched, got:  '%s'", content)
+    }
+}
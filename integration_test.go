@@ -0,0 +1,201 @@
+//go:build integration
+
+// These tests run the full pipeline against real files in a temporary
+// directory rather than MemMapFs. They touch the disk, so they only
+// build with the integration tag:
+//
+//     go test -tags integration ./...
+
+package shredder
+
+import (
+    "bytes"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "runtime"
+    "shredder/faultfs"
+    "testing"
+)
+
+func writeRealFile(t *testing.T, path string, content []byte) {
+    err := os.MkdirAll(filepath.Dir(path), 0755)
+    if err == nil {
+        err = os.WriteFile(path, content, 0644)
+    }
+    if err != nil {
+        t.Fatalf("Could not create test file: %v", err)
+    }
+}
+
+func TestIntegrationShredFileSyncsEveryPass(t *testing.T) {
+    fs := faultfs.New(afero.NewOsFs(), faultfs.Faults{})
+    AppFs = fs
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    path := filepath.Join(t.TempDir(), "secret.txt")
+    original := bytes.Repeat([]byte("Some bytes that need replacing "), 1000)
+    writeRealFile(t, path, original)
+
+    // When
+    err := ShredFile(path, Options{Scheme: SchemeDoD, Verify: true, BufferSize: 4096})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if fs.Syncs() != len(SchemeDoD.Passes) {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", len(SchemeDoD.Passes), fs.Syncs())
+    }
+    content, _ := os.ReadFile(path)
+    if len(content) != len(original) || bytes.Equal(content, original) {
+        t.Errorf("Test failed, expected different bytes of the same length")
+    }
+}
+
+func TestIntegrationShredDirRenamesAndUnlinks(t *testing.T) {
+    fs := &fsThatRecordsRenames{Fs: afero.NewOsFs(), renames: map[string]string{}}
+    AppFs = fs
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    root := filepath.Join(t.TempDir(), "tree")
+    for _, name := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt"} {
+        writeRealFile(t, filepath.Join(root, name), []byte("Some bytes that need replacing"))
+    }
+
+    // When
+    err := ShredDir(root, Options{Remove: true, Verify: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if _, statErr := os.Stat(root); !os.IsNotExist(statErr) {
+        t.Errorf("Test failed, expected the tree to be removed, got:  '%v'", statErr)
+    }
+    for _, name := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt"} {
+        path := filepath.Join(root, name)
+        renamed, ok := fs.renames[path]
+        if !ok || filepath.Dir(renamed) != filepath.Dir(path) || filepath.Base(renamed) == filepath.Base(path) {
+            t.Errorf("Test failed, expected %s renamed within its directory, got:  '%s'", path, renamed)
+        }
+    }
+}
+
+func TestIntegrationShredDirLeavesSymlinkTargetsAlone(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    outside := filepath.Join(dir, "outside.txt")
+    writeRealFile(t, outside, []byte("Not part of the tree"))
+    root := filepath.Join(dir, "tree")
+    writeRealFile(t, filepath.Join(root, "inside.txt"), []byte("Some bytes that need replacing"))
+    if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+        t.Skipf("Symlinks not supported here: %v", err)
+    }
+
+    // When
+    err := ShredDir(root, Options{Remove: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := os.ReadFile(outside)
+    if string(content) != "Not part of the tree" {
+        t.Errorf("Test failed, expected the symlink target to be untouched, got:  '%q'", content)
+    }
+}
+
+func TestIntegrationShredFileFillsSparseFiles(t *testing.T) {
+    // Given
+    path := filepath.Join(t.TempDir(), "sparse.img")
+    file, _ := os.Create(path)
+    file.Truncate(8 << 20)
+    file.Close()
+
+    before, err := Inspect(path)
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if !before.Sparse {
+        t.Skip("Filesystem does not create sparse files")
+    }
+
+    // When
+    err = ShredFile(path, Options{Passes: 1})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    after, _ := Inspect(path)
+    if after.Size != 8<<20 || after.Sparse {
+        t.Errorf("Test failed, expected a fully allocated 8 MiB file, got:  '%+v'", after)
+    }
+}
+
+func TestIntegrationShredLinksRemovesEveryName(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    names := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+    writeRealFile(t, names[0], []byte("Some bytes that need replacing"))
+    if err := os.Link(names[0], names[1]); err != nil {
+        t.Skipf("Hard links not supported here: %v", err)
+    }
+
+    // When
+    err := ShredLinks(names, Options{Verify: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    entries, _ := os.ReadDir(dir)
+    if len(entries) != 0 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 0, len(entries))
+    }
+}
+
+func TestIntegrationRotateSecretFile(t *testing.T) {
+    if !renameOverOpenFile {
+        t.Skip("Cannot rename over an open file on this platform")
+    }
+
+    // Given
+    path := filepath.Join(t.TempDir(), "token")
+    writeRealFile(t, path, []byte("old secret"))
+
+    // When
+    err := RotateSecretFile(path, []byte("new secret"))
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := os.ReadFile(path)
+    if string(content) != "new secret" {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", "new secret", content)
+    }
+}
+
+func TestIntegrationAppendModeHandlesAreRejected(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("Append mode cannot be detected on this platform")
+    }
+
+    // Given
+    path := filepath.Join(t.TempDir(), "log.txt")
+    writeRealFile(t, path, []byte("Some bytes that need replacing"))
+    file, _ := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0644)
+    defer file.Close()
+
+    // When
+    err := OverwriteStream(file, 30, Options{})
+
+    // Then
+    if err != ErrAppendModeHandle {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrAppendModeHandle, err)
+    }
+}
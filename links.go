@@ -0,0 +1,83 @@
+package shredder
+
+import (
+    "context"
+    "fmt"
+    "os"
+)
+
+// ShredLinks destroys a file known by several hard-linked names. The
+// shared contents are overwritten once, through the first name, and then
+// every name is truncated, renamed and unlinked as with opts.Remove.
+// Every name must refer to the same file, or nothing is touched and
+// ErrNotSameFile is returned. As every name is removed, a nonzero
+// opts.Offset is rejected with ErrInvalidRange.
+func ShredLinks(names []string, opts Options) error {
+    opts.ProfileLabels = false
+    return ShredLinksContext(context.Background(), names, opts)
+}
+
+// ShredLinksContext is ShredLinks, stopping with the context's cause if
+// it is cancelled before the contents are fully overwritten, in which
+// case no names are removed
+func ShredLinksContext(ctx context.Context, names []string, opts Options) error {
+    err := checkRemovable(opts)
+    if err != nil {
+        return err
+    }
+
+    names = uniqueNames(names)
+    if len(names) == 0 {
+        return fmt.Errorf("Error shredding links: no names given")
+    }
+
+    first, err := AppFs.Stat(names[0])
+    if err != nil {
+        return wrapError(ErrStat, err)
+    }
+
+    if first.Mode()&os.ModeDevice != 0 {
+        return fmt.Errorf("%w: %s is a device", ErrRemove, names[0])
+    }
+
+    for _, name := range names[1:] {
+        info, err := AppFs.Stat(name)
+        if err != nil {
+            return wrapError(ErrStat, err)
+        }
+
+        if !os.SameFile(first, info) {
+            return fmt.Errorf("%w: %s and %s", ErrNotSameFile, names[0], name)
+        }
+    }
+
+    opts.Remove = false
+    err = ShredContext(ctx, names[0], opts)
+    if err != nil {
+        return err
+    }
+
+    for _, name := range names {
+        err = removeShredded(name)
+        if err != nil {
+            return fmt.Errorf("Error removing %s: %w", name, err)
+        }
+    }
+
+    return nil
+}
+
+// uniqueNames drops repeated names, keeping the first of each
+func uniqueNames(names []string) []string {
+    seen := make(map[string]bool, len(names))
+    unique := make([]string, 0, len(names))
+
+    for _, name := range names {
+        if !seen[name] {
+            seen[name] = true
+            unique = append(unique, name)
+        }
+    }
+
+    return unique
+}
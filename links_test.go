@@ -0,0 +1,101 @@
+package shredder
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func createLinkedFile(t *testing.T, names ...string) {
+    err := os.WriteFile(names[0], []byte("Some bytes that need replacing"), 0644)
+    if err != nil {
+        t.Fatalf("Could not create test file: %v", err)
+    }
+
+    for _, name := range names[1:] {
+        if err := os.Link(names[0], name); err != nil {
+            t.Skipf("Hard links not supported here: %v", err)
+        }
+    }
+}
+
+func TestShredLinksOverwritesOnceAndRemovesEveryName(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewOsFs(), writes: &writes}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    dir := t.TempDir()
+    names := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")}
+    createLinkedFile(t, names...)
+    writes = 0
+
+    // When
+    err := ShredLinks(names, Options{Passes: 2})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if writes != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, writes)
+    }
+    entries, _ := os.ReadDir(dir)
+    if len(entries) != 0 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 0, len(entries))
+    }
+}
+
+func TestShredLinksRefusesDifferentFiles(t *testing.T) {
+    // Given
+    dir := t.TempDir()
+    first := filepath.Join(dir, "first")
+    second := filepath.Join(dir, "second")
+    createLinkedFile(t, first)
+    createLinkedFile(t, second)
+
+    // When
+    err := ShredLinks([]string{first, second}, Options{})
+
+    // Then
+    if !errors.Is(err, ErrNotSameFile) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrNotSameFile, err)
+    }
+    for _, name := range []string{first, second} {
+        content, _ := os.ReadFile(name)
+        if string(content) != "Some bytes that need replacing" {
+            t.Errorf("Test failed, expected %s to be untouched, got:  '%q'", name, content)
+        }
+    }
+}
+
+func TestShredLinksIgnoresRepeatedNames(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "only", []byte("Some bytes that need replacing"), 0644)
+
+    // When
+    err := ShredLinks([]string{"only", "only"}, Options{})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if exists, _ := afero.Exists(AppFs, "only"); exists {
+        t.Errorf("Test failed, expected the file to be removed")
+    }
+}
+
+func TestShredLinksWithNoNamesReturnsError(t *testing.T) {
+    // When
+    err := ShredLinks(nil, Options{})
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+}
@@ -0,0 +1,81 @@
+package shredder
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "unsafe"
+)
+
+// WipeMapped overwrites a memory-mapped region with random bytes,
+// flushing each pass to the backing file with a synchronous msync so
+// both the memory and the pages on disk are scrubbed. b must be a shared
+// mapping, such as one returned by syscall.Mmap with MAP_SHARED, or any
+// part of one; the whole pages it touches are synced. It is only
+// supported on Linux; elsewhere it returns errors.ErrUnsupported without
+// writing anything.
+func WipeMapped(b []byte) error {
+    if !canWipeMapped {
+        return errors.ErrUnsupported
+    }
+
+    for i := 0; i < ShredOverwriteCount; i++ {
+        err := interrupted(context.Background())
+        if err != nil {
+            return err
+        }
+
+        // Fill in place rather than through GenerateRandomBytes so no
+        // second copy of the region is allocated
+        err = fillRandom(b)
+        if err != nil {
+            return err
+        }
+
+        err = msync(b)
+        if err != nil {
+            return fmt.Errorf("Error syncing mapped region: %w", err)
+        }
+    }
+
+    return nil
+}
+
+// WipeAndUnmap wipes a mapped region like WipeMapped, then tells the
+// kernel to drop its pages and unmaps it. b must not be used afterwards.
+// syscall.Munmap only accepts the slice syscall.Mmap returned, so b must
+// be exactly that slice, not part of it. A slice that does not start on
+// a page boundary or stops short of its capacity is rejected before
+// anything is written.
+func WipeAndUnmap(b []byte) error {
+    if !pageAligned(b) {
+        return fmt.Errorf("Error wiping mapped region: start is not page-aligned")
+    }
+
+    if len(b) != cap(b) {
+        return fmt.Errorf("Error wiping mapped region: not the whole mapping")
+    }
+
+    err := WipeMapped(b)
+    if err != nil {
+        return err
+    }
+
+    err = dropPages(b)
+    if err != nil {
+        return fmt.Errorf("Error dropping mapped pages: %w", err)
+    }
+
+    err = unmap(b)
+    if err != nil {
+        return fmt.Errorf("Error unmapping region: %w", err)
+    }
+
+    return nil
+}
+
+// pageAligned reports whether b starts on a page boundary
+func pageAligned(b []byte) bool {
+    return len(b) == 0 || uintptr(unsafe.Pointer(&b[0]))%uintptr(os.Getpagesize()) == 0
+}
@@ -0,0 +1,35 @@
+package shredder
+
+import (
+    "syscall"
+    "unsafe"
+)
+
+const canWipeMapped = true
+
+func msync(b []byte) error {
+    if len(b) == 0 {
+        return nil
+    }
+
+    // msync only takes page-aligned addresses, so widen the range to
+    // the start of its first page. Pages are synced whole regardless.
+    start := uintptr(unsafe.Pointer(&b[0]))
+    aligned := start &^ uintptr(syscall.Getpagesize()-1)
+
+    _, _, errno := syscall.Syscall(syscall.SYS_MSYNC,
+        aligned, uintptr(len(b))+start-aligned, syscall.MS_SYNC)
+    if errno != 0 {
+        return errno
+    }
+
+    return nil
+}
+
+func dropPages(b []byte) error {
+    return syscall.Madvise(b, syscall.MADV_DONTNEED)
+}
+
+func unmap(b []byte) error {
+    return syscall.Munmap(b)
+}
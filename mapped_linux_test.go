@@ -0,0 +1,158 @@
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+)
+
+func mapTestFile(t *testing.T, content []byte) (string, []byte) {
+    path := filepath.Join(t.TempDir(), "mapped")
+    os.WriteFile(path, content, 0600)
+
+    file, err := os.OpenFile(path, os.O_RDWR, 0600)
+    if err != nil {
+        t.Fatalf("Could not open test file: %v", err)
+    }
+    defer file.Close()
+
+    mapped, err := syscall.Mmap(int(file.Fd()), 0, len(content),
+        syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+    if err != nil {
+        t.Fatalf("Could not map test file: %v", err)
+    }
+
+    return path, mapped
+}
+
+func TestWipeMappedScrubsMemoryAndBackingFile(t *testing.T) {
+    // Given
+    original := []byte("Some mapped bytes that need replacing")
+    path, mapped := mapTestFile(t, original)
+    defer syscall.Munmap(mapped)
+
+    // When
+    err := WipeMapped(mapped)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if bytes.Equal(mapped, original) {
+        t.Errorf("Test failed, expected mapped memory to differ")
+    }
+    onDisk, _ := os.ReadFile(path)
+    if !bytes.Equal(onDisk, mapped) {
+        t.Errorf("Test failed, expected: '%x', got:  '%x'", mapped, onDisk)
+    }
+}
+
+func TestWipeAndUnmapScrubsBackingFile(t *testing.T) {
+    // Given
+    original := []byte("Some mapped bytes that need replacing")
+    path, mapped := mapTestFile(t, original)
+
+    // When
+    err := WipeAndUnmap(mapped)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    onDisk, _ := os.ReadFile(path)
+    if len(onDisk) != len(original) || bytes.Equal(onDisk, original) {
+        t.Errorf("Test failed, expected backing file to be overwritten, got:  '%x'", onDisk)
+    }
+}
+
+func TestWipeMappedWithEmptyRegion(t *testing.T) {
+    // When
+    err := WipeMapped([]byte{})
+
+    // Then
+    if err != nil {
+        t.Errorf("Test failed, unexpected error: %v", err)
+    }
+}
+
+func TestWipeMappedScrubsPartOfAPage(t *testing.T) {
+    // Given
+    original := bytes.Repeat([]byte("Some mapped bytes that need replacing "), 10)
+    path, mapped := mapTestFile(t, original)
+    defer syscall.Munmap(mapped)
+
+    // When
+    err := WipeMapped(mapped[100:200])
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    onDisk, _ := os.ReadFile(path)
+    if !bytes.Equal(onDisk[:100], original[:100]) || !bytes.Equal(onDisk[200:], original[200:]) {
+        t.Errorf("Test failed, expected bytes outside the region to be untouched")
+    }
+    if bytes.Equal(onDisk[100:200], original[100:200]) {
+        t.Errorf("Test failed, expected the region to be overwritten on disk")
+    }
+}
+
+func TestWipeAndUnmapRejectsUnalignedRegions(t *testing.T) {
+    // Given
+    original := []byte("Some mapped bytes that need replacing")
+    _, mapped := mapTestFile(t, original)
+    defer syscall.Munmap(mapped)
+
+    // When
+    err := WipeAndUnmap(mapped[10:])
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    if !bytes.Equal(mapped, original) {
+        t.Errorf("Test failed, expected nothing written, got:  '%s'", mapped)
+    }
+}
+
+func TestWipeAndUnmapRejectsPartOfAMapping(t *testing.T) {
+    // Given
+    original := bytes.Repeat([]byte{0xAB}, 2*os.Getpagesize())
+    _, mapped := mapTestFile(t, original)
+    defer syscall.Munmap(mapped)
+
+    // When
+    err := WipeAndUnmap(mapped[:os.Getpagesize()])
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    if !bytes.Equal(mapped, original) {
+        t.Errorf("Test failed, expected nothing written")
+    }
+}
+
+func TestWipeMappedStopsOnEmergencyStop(t *testing.T) {
+    defer ClearEmergencyStop()
+
+    // Given
+    original := []byte("Some mapped bytes that need replacing")
+    _, mapped := mapTestFile(t, original)
+    defer syscall.Munmap(mapped)
+    EmergencyStop()
+
+    // When
+    err := WipeMapped(mapped)
+
+    // Then
+    if !errors.Is(err, ErrEmergencyStop) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrEmergencyStop, err)
+    }
+    if !bytes.Equal(mapped, original) {
+        t.Errorf("Test failed, expected nothing written, got:  '%s'", mapped)
+    }
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package shredder
+
+import "errors"
+
+const canWipeMapped = false
+
+func msync(b []byte) error {
+    return errors.ErrUnsupported
+}
+
+func dropPages(b []byte) error {
+    return errors.ErrUnsupported
+}
+
+func unmap(b []byte) error {
+    return errors.ErrUnsupported
+}
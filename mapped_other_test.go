@@ -0,0 +1,26 @@
+//go:build !linux
+
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+)
+
+func TestWipeMappedIsUnsupportedWithoutWriting(t *testing.T) {
+    // Given
+    original := []byte("Some bytes that need replacing")
+    region := append([]byte{}, original...)
+
+    // When
+    err := WipeMapped(region)
+
+    // Then
+    if !errors.Is(err, errors.ErrUnsupported) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", errors.ErrUnsupported, err)
+    }
+    if !bytes.Equal(region, original) {
+        t.Errorf("Test failed, expected nothing written, got:  '%s'", region)
+    }
+}
@@ -0,0 +1,101 @@
+package shredder
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// A Matcher decides whether a file is a target for shredding. Matchers
+// are built from small predicates and combined with And, Or and Not.
+type Matcher interface {
+    Match(path string, info os.FileInfo) bool
+}
+
+// MatcherFunc adapts an ordinary function to the Matcher interface
+type MatcherFunc func(path string, info os.FileInfo) bool
+
+func (f MatcherFunc) Match(path string, info os.FileInfo) bool {
+    return f(path, info)
+}
+
+// And matches when every one of the given matchers does
+func And(matchers ...Matcher) Matcher {
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        for _, matcher := range matchers {
+            if !matcher.Match(path, info) {
+                return false
+            }
+        }
+        return true
+    })
+}
+
+// Or matches when at least one of the given matchers does
+func Or(matchers ...Matcher) Matcher {
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        for _, matcher := range matchers {
+            if matcher.Match(path, info) {
+                return true
+            }
+        }
+        return false
+    })
+}
+
+// Not matches exactly the files the given matcher does not
+func Not(matcher Matcher) Matcher {
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        return !matcher.Match(path, info)
+    })
+}
+
+// Older matches files last modified more than age ago
+func Older(age time.Duration) Matcher {
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        return time.Since(info.ModTime()) > age
+    })
+}
+
+// LargerThan matches files of more than size bytes
+func LargerThan(size int64) Matcher {
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        return info.Size() > size
+    })
+}
+
+// OwnedBy matches files owned by the given user id. On platforms without
+// unix ownership it never matches.
+func OwnedBy(uid int) Matcher {
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        owner, ok := fileOwner(info)
+        return ok && owner == uid
+    })
+}
+
+// NameGlob matches the base name of a file against a filepath.Match
+// pattern. The pattern is checked up front, so a typo is an error
+// wrapping filepath.ErrBadPattern rather than a matcher that silently
+// matches nothing.
+func NameGlob(pattern string) (Matcher, error) {
+    if _, err := filepath.Match(pattern, ""); err != nil {
+        return nil, fmt.Errorf("Error in glob pattern %q: %w", pattern, err)
+    }
+
+    return MatcherFunc(func(path string, info os.FileInfo) bool {
+        matched, _ := filepath.Match(pattern, filepath.Base(path))
+        return matched
+    }), nil
+}
+
+// MustNameGlob is NameGlob for patterns fixed in the source, panicking
+// if the pattern is bad
+func MustNameGlob(pattern string) Matcher {
+    matcher, err := NameGlob(pattern)
+    if err != nil {
+        panic(err.Error())
+    }
+
+    return matcher
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package shredder
+
+import "os"
+
+func fileOwner(info os.FileInfo) (int, bool) {
+    return 0, false
+}
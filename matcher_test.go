@@ -0,0 +1,127 @@
+package shredder
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func statInMemory(t *testing.T, name string, content string, modTime time.Time) os.FileInfo {
+    fs := afero.NewMemMapFs()
+    afero.WriteFile(fs, name, []byte(content), 0644)
+    fs.Chtimes(name, modTime, modTime)
+
+    info, err := fs.Stat(name)
+    if err != nil {
+        t.Fatalf("Could not stat test file: %v", err)
+    }
+
+    return info
+}
+
+func TestMatcherCombinators(t *testing.T) {
+    // Given
+    info := statInMemory(t, "report.pdf", "twelve bytes", time.Now())
+    yes := MatcherFunc(func(string, os.FileInfo) bool { return true })
+    no := Not(yes)
+
+    cases := []struct {
+        name     string
+        matcher  Matcher
+        expected bool
+    }{
+        {"and all true", And(yes, yes), true},
+        {"and one false", And(yes, no), false},
+        {"and empty", And(), true},
+        {"or one true", Or(no, yes), true},
+        {"or all false", Or(no, no), false},
+        {"or empty", Or(), false},
+        {"not", Not(no), true},
+    }
+
+    for _, c := range cases {
+        // When
+        actual := c.matcher.Match("report.pdf", info)
+
+        // Then
+        if actual != c.expected {
+            t.Errorf("Test failed for %s, expected: '%t', got:  '%t'", c.name, c.expected, actual)
+        }
+    }
+}
+
+func TestMatcherPredicates(t *testing.T) {
+    // Given
+    old := statInMemory(t, "/data/old.log", "twelve bytes", time.Now().Add(-48*time.Hour))
+    recent := statInMemory(t, "/data/new.txt", "tiny", time.Now())
+
+    cases := []struct {
+        name     string
+        matcher  Matcher
+        path     string
+        info     os.FileInfo
+        expected bool
+    }{
+        {"older matches old file", Older(24 * time.Hour), "/data/old.log", old, true},
+        {"older skips recent file", Older(24 * time.Hour), "/data/new.txt", recent, false},
+        {"larger matches big file", LargerThan(4), "/data/old.log", old, true},
+        {"larger skips equal size", LargerThan(4), "/data/new.txt", recent, false},
+        {"glob matches base name", MustNameGlob("*.log"), "/data/old.log", old, true},
+        {"glob skips other names", MustNameGlob("*.log"), "/data/new.txt", recent, false},
+        {"owner unknown for memory files", OwnedBy(os.Getuid()), "/data/old.log", old, false},
+    }
+
+    for _, c := range cases {
+        // When
+        actual := c.matcher.Match(c.path, c.info)
+
+        // Then
+        if actual != c.expected {
+            t.Errorf("Test failed for %s, expected: '%t', got:  '%t'", c.name, c.expected, actual)
+        }
+    }
+}
+
+func TestOwnedByMatchesRealFiles(t *testing.T) {
+    // Given
+    file, err := os.CreateTemp(t.TempDir(), "owned")
+    if err != nil {
+        t.Fatalf("Could not create temp file: %v", err)
+    }
+    file.Close()
+    info, _ := os.Stat(file.Name())
+
+    // When
+    _, supported := fileOwner(info)
+    matched := OwnedBy(os.Getuid()).Match(file.Name(), info)
+
+    // Then
+    if matched != supported {
+        t.Errorf("Test failed, expected: '%t', got:  '%t'", supported, matched)
+    }
+}
+
+func TestNameGlobWithBadPatternReturnsError(t *testing.T) {
+    // When
+    matcher, err := NameGlob("[")
+
+    // Then
+    if !errors.Is(err, filepath.ErrBadPattern) || matcher != nil {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", filepath.ErrBadPattern, err)
+    }
+}
+
+func TestMustNameGlobWithBadPatternCausesPanic(t *testing.T) {
+    // Then
+    defer func() {
+        if r := recover(); r == nil {
+            t.Errorf("The code did not panic")
+        }
+    }()
+
+    // When
+    MustNameGlob("[")
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package shredder
+
+import (
+    "os"
+    "syscall"
+)
+
+func fileOwner(info os.FileInfo) (int, bool) {
+    stat, ok := info.Sys().(*syscall.Stat_t)
+    if !ok {
+        return 0, false
+    }
+
+    return int(stat.Uid), true
+}
@@ -0,0 +1,18 @@
+package shredder
+
+import (
+    "fmt"
+    "syscall"
+)
+
+// LockMemory locks all current and future pages of the process into RAM
+// so they cannot be swapped out. It usually needs CAP_IPC_LOCK or a
+// generous RLIMIT_MEMLOCK. The returned function unlocks them again.
+func LockMemory() (func() error, error) {
+    err := syscall.Mlockall(syscall.MCL_CURRENT | syscall.MCL_FUTURE)
+    if err != nil {
+        return nil, fmt.Errorf("Error locking process memory: %w", err)
+    }
+
+    return syscall.Munlockall, nil
+}
@@ -0,0 +1,24 @@
+package shredder
+
+import (
+    "errors"
+    "syscall"
+    "testing"
+)
+
+func TestLockMemoryAndUnlock(t *testing.T) {
+    // When
+    unlock, err := LockMemory()
+
+    // Then
+    // Unprivileged processes may not be allowed to lock their memory
+    if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOMEM) {
+        t.Skipf("Memory locking not permitted here: %v", err)
+    }
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if err := unlock(); err != nil {
+        t.Errorf("Test failed, unexpected error: %v", err)
+    }
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package shredder
+
+import "errors"
+
+// LockMemory is only supported on Linux
+func LockMemory() (func() error, error) {
+    return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,110 @@
+package shredder
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+)
+
+// SecureMove copies src to dst, checks the copy against the source by
+// SHA-256, syncs it and its directory, and only then shreds src and
+// removes it as ShredFile does with Remove. Because it copies rather than renames, dst may be on a different
+// filesystem. An existing dst is never overwritten.
+func SecureMove(src string, dst string) error {
+    if err := interrupted(context.Background()); err != nil {
+        return err
+    }
+
+    sourceHash, perm, err := copyFileWithHash(src, dst)
+    if err != nil {
+        return err
+    }
+
+    destinationHash, err := hashFile(dst)
+    if err != nil {
+        return err
+    }
+
+    if !bytes.Equal(sourceHash, destinationHash) {
+        // Keep the source - it's the only good copy
+        AppFs.Remove(dst)
+        return fmt.Errorf("Error verifying copy of %s: destination hash does not match", src)
+    }
+
+    err = AppFs.Chmod(dst, perm)
+    if err != nil {
+        return fmt.Errorf("Error setting destination permissions: %w", err)
+    }
+
+    // The copy's directory entry must be on disk before the source is
+    // destroyed, or a crash could leave neither
+    err = syncDir(filepath.Dir(dst))
+    if err != nil {
+        return err
+    }
+
+    // Removing through ShredFile truncates and renames the source first,
+    // so its name and size go with its contents
+    return ShredFile(src, Options{Remove: true})
+}
+
+// copyFileWithHash copies src to a newly created dst, returning the hash
+// of the bytes read from src and its permissions
+func copyFileWithHash(src string, dst string) ([]byte, os.FileMode, error) {
+    source, err := AppFs.Open(src)
+    if err != nil {
+        return nil, 0, wrapError(ErrOpen, err)
+    }
+    defer source.Close()
+
+    sourceInfo, err := source.Stat()
+    if err != nil {
+        return nil, 0, wrapError(ErrStat, err)
+    }
+    if !sourceInfo.Mode().IsRegular() {
+        return nil, 0, fmt.Errorf("Error moving %s: not a regular file", src)
+    }
+
+    // Create the copy owner-only until it has been verified
+    destination, err := AppFs.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+    if err != nil {
+        return nil, 0, fmt.Errorf("Error creating destination file: %w", err)
+    }
+
+    hash := sha256.New()
+    _, err = io.Copy(destination, io.TeeReader(source, hash))
+    if err == nil {
+        err = destination.Sync()
+    }
+    closeErr := destination.Close()
+    if err == nil {
+        err = closeErr
+    }
+
+    if err != nil {
+        AppFs.Remove(dst)
+        return nil, 0, fmt.Errorf("Error copying to destination: %w", err)
+    }
+
+    return hash.Sum(nil), sourceInfo.Mode().Perm(), nil
+}
+
+func hashFile(pathToFile string) ([]byte, error) {
+    file, err := AppFs.Open(pathToFile)
+    if err != nil {
+        return nil, wrapError(ErrOpen, err)
+    }
+    defer file.Close()
+
+    hash := sha256.New()
+    _, err = io.Copy(hash, file)
+    if err != nil {
+        return nil, wrapError(ErrRead, err)
+    }
+
+    return hash.Sum(nil), nil
+}
@@ -0,0 +1,160 @@
+package shredder
+
+import (
+    "bytes"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+)
+
+func TestSecureMoveCopiesAndRemovesSource(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    content := []byte("Some archive that needs moving")
+    afero.WriteFile(AppFs, "/old/archive.tar", content, 0640)
+
+    // When
+    err := SecureMove("/old/archive.tar", "/new/archive.tar")
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    moved, _ := afero.ReadFile(AppFs, "/new/archive.tar")
+    if !bytes.Equal(moved, content) {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", content, moved)
+    }
+    if exists, _ := afero.Exists(AppFs, "/old/archive.tar"); exists {
+        t.Errorf("Test failed, expected source to be removed")
+    }
+    info, _ := AppFs.Stat("/new/archive.tar")
+    if info.Mode().Perm() != 0640 {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", os.FileMode(0640), info.Mode().Perm())
+    }
+}
+
+// Records every rename, then passes it on
+type fsThatRecordsRenames struct {
+    afero.Fs
+    renames map[string]string
+    mu      sync.Mutex
+}
+
+func (fs *fsThatRecordsRenames) Rename(oldname, newname string) error {
+    fs.mu.Lock()
+    fs.renames[oldname] = newname
+    fs.mu.Unlock()
+    return fs.Fs.Rename(oldname, newname)
+}
+
+func (fs *fsThatRecordsRenames) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+    return lstatThrough(fs.Fs, name)
+}
+
+func TestSecureMoveRenamesSourceBeforeRemovingIt(t *testing.T) {
+    fs := &fsThatRecordsRenames{Fs: afero.NewMemMapFs(), renames: map[string]string{}}
+    AppFs = fs
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/old/archive.tar", []byte("Some archive that needs moving"), 0640)
+
+    // When
+    err := SecureMove("/old/archive.tar", "/new/archive.tar")
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    renamed, ok := fs.renames["/old/archive.tar"]
+    if !ok || filepath.Dir(renamed) != "/old" {
+        t.Errorf("Test failed, expected the source renamed within its directory, got:  '%s'", renamed)
+    }
+    if exists, _ := afero.Exists(AppFs, renamed); exists {
+        t.Errorf("Test failed, expected the renamed source to be removed")
+    }
+}
+
+func TestSecureMoveRefusesToOverwriteDestination(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "src", []byte("source"), 0644)
+    afero.WriteFile(AppFs, "dst", []byte("existing"), 0644)
+
+    // When
+    err := SecureMove("src", "dst")
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    source, _ := afero.ReadFile(AppFs, "src")
+    existing, _ := afero.ReadFile(AppFs, "dst")
+    if string(source) != "source" || string(existing) != "existing" {
+        t.Errorf("Test failed, expected both files untouched, got:  '%s' and '%s'", source, existing)
+    }
+}
+
+func TestSecureMoveMissingSourceReturnsError(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    err := SecureMove("nonexistent_file.txt", "dst")
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    if exists, _ := afero.Exists(AppFs, "dst"); exists {
+        t.Errorf("Test failed, expected no destination to be created")
+    }
+}
+
+// Corrupts everything written through it, as a failing disk might
+type fsThatCorruptsWrites struct {
+    afero.Fs
+}
+
+func (fs fsThatCorruptsWrites) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    file, err := fs.Fs.OpenFile(name, flag, perm)
+    return fileThatCorruptsWrites{file}, err
+}
+
+type fileThatCorruptsWrites struct {
+    afero.File
+}
+
+func (f fileThatCorruptsWrites) Write(p []byte) (int, error) {
+    return f.File.Write(bytes.ToUpper(p))
+}
+
+func TestSecureMoveKeepsSourceWhenCopyDoesNotVerify(t *testing.T) {
+    memFs := afero.NewMemMapFs()
+    AppFs = fsThatCorruptsWrites{memFs}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(memFs, "src", []byte("lower case content"), 0644)
+
+    // When
+    err := SecureMove("src", "dst")
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    source, _ := afero.ReadFile(memFs, "src")
+    if string(source) != "lower case content" {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", "lower case content", source)
+    }
+    if exists, _ := afero.Exists(memFs, "dst"); exists {
+        t.Errorf("Test failed, expected unverified copy to be removed")
+    }
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package shredder
+
+import (
+    "github.com/spf13/afero"
+    "testing"
+)
+
+func TestSecureMoveSyncsDestinationDirectoryBeforeShredding(t *testing.T) {
+    var synced []string
+    AppFs = fsThatRecordsSyncs{Fs: afero.NewMemMapFs(), synced: &synced}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/old/archive.tar", []byte("Some archive that needs moving"), 0640)
+    AppFs.MkdirAll("/new", 0755)
+    synced = nil
+
+    // When
+    err := SecureMove("/old/archive.tar", "/new/archive.tar")
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    // The copy, then its directory, then each pass over the source
+    if len(synced) < 3 || synced[0] != "/new/archive.tar" || synced[1] != "/new" || synced[2] != "/old/archive.tar" {
+        t.Errorf("Test failed, expected the destination directory synced before shredding, got:  '%v'", synced)
+    }
+}
@@ -0,0 +1,77 @@
+package shredder
+
+import (
+    "context"
+    "sync/atomic"
+    "time"
+)
+
+// A ProgressEvent reports how far an overwrite has got. Byte counts are
+// relative to the range being overwritten, not the whole file.
+type ProgressEvent struct {
+    // The file being shredded; empty for OverwriteStream
+    Path string
+
+    // The current pass, counting from 1
+    Pass        int
+    TotalPasses int
+
+    // Bytes written so far in the current pass
+    PassBytesWritten int64
+
+    // Bytes written so far, and in total, across all passes
+    BytesWritten int64
+    TotalBytes   int64
+
+    // With Options.PreScan, progress through the whole of ShredDir's
+    // tree: files finished out of those found, bytes written across every
+    // pass of every file out of those expected, and time since the
+    // shredding started, from which to estimate how long is left. Zero
+    // otherwise.
+    TreeFilesDone    int
+    TreeFiles        int
+    TreeBytesWritten int64
+    TreeTotalBytes   int64
+    TreeElapsed      time.Duration
+}
+
+// treeProgress adds the totals from ShredDir's pre-scan to the progress
+// events of the files it shreds, several at a time
+type treeProgress struct {
+    files   int
+    bytes   int64
+    started time.Time
+
+    filesDone    atomic.Int64
+    bytesWritten atomic.Int64
+}
+
+func newTreeProgress(ctx context.Context, root string, opts Options) (*treeProgress, error) {
+    estimation, err := estimate(ctx, root, opts)
+    if err != nil {
+        return nil, err
+    }
+
+    return &treeProgress{files: estimation.Files, bytes: estimation.WriteBytes, started: time.Now()}, nil
+}
+
+// forFile wraps progress for one file, whose events come from a single
+// goroutine, so they carry the totals for the whole tree too
+func (tree *treeProgress) forFile(progress func(ProgressEvent)) func(ProgressEvent) {
+    var reported int64
+    return func(event ProgressEvent) {
+        written := tree.bytesWritten.Add(event.BytesWritten - reported)
+        reported = event.BytesWritten
+
+        event.TreeFilesDone = int(tree.filesDone.Load())
+        event.TreeFiles = tree.files
+        event.TreeBytesWritten = written
+        event.TreeTotalBytes = tree.bytes
+        event.TreeElapsed = time.Since(tree.started)
+        progress(event)
+    }
+}
+
+func (tree *treeProgress) fileDone() {
+    tree.filesDone.Add(1)
+}
@@ -0,0 +1,270 @@
+package shredder
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "sync"
+    "testing"
+)
+
+func TestOverwriteStreamReportsProgress(t *testing.T) {
+    // Given
+    var events []ProgressEvent
+    opts := Options{Passes: 2, BufferSize: 4, Progress: func(event ProgressEvent) {
+        events = append(events, event)
+    }}
+
+    // When
+    err := OverwriteStream(&writerThatCopiesChunks{}, 10, opts)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    expected := []ProgressEvent{
+        {Pass: 1, TotalPasses: 2, PassBytesWritten: 4, BytesWritten: 4, TotalBytes: 20},
+        {Pass: 1, TotalPasses: 2, PassBytesWritten: 8, BytesWritten: 8, TotalBytes: 20},
+        {Pass: 1, TotalPasses: 2, PassBytesWritten: 10, BytesWritten: 10, TotalBytes: 20},
+        {Pass: 2, TotalPasses: 2, PassBytesWritten: 4, BytesWritten: 14, TotalBytes: 20},
+        {Pass: 2, TotalPasses: 2, PassBytesWritten: 8, BytesWritten: 18, TotalBytes: 20},
+        {Pass: 2, TotalPasses: 2, PassBytesWritten: 10, BytesWritten: 20, TotalBytes: 20},
+    }
+    if len(events) != len(expected) {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", len(expected), len(events))
+    }
+    for i := range expected {
+        if events[i] != expected[i] {
+            t.Errorf("Test failed for event %d, expected: '%+v', got:  '%+v'", i, expected[i], events[i])
+        }
+    }
+}
+
+func TestShredFileProgressIncludesPath(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    var paths []string
+    opts := Options{Passes: 1, Progress: func(event ProgressEvent) {
+        paths = append(paths, event.Path)
+    }}
+
+    // When
+    ShredFile("test.txt", opts)
+
+    // Then
+    if len(paths) != 1 || paths[0] != "test.txt" {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", []string{"test.txt"}, paths)
+    }
+}
+
+func TestOverwriteStreamContextStopsWhenCancelled(t *testing.T) {
+    // Given
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    writer := &writerThatCopiesChunks{}
+
+    // Cancel part way through the first pass
+    opts := Options{Passes: 3, BufferSize: 4, Progress: func(event ProgressEvent) {
+        if event.BytesWritten == 8 {
+            cancel()
+        }
+    }}
+
+    // When
+    err := OverwriteStreamContext(ctx, writer, 16, opts)
+
+    // Then
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", context.Canceled, err)
+    }
+    if len(writer.chunks) != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, len(writer.chunks))
+    }
+}
+
+func TestShredContextCancelledDoesNotRemoveFile(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    original := []byte("Some bytes that need replacing")
+    afero.WriteFile(AppFs, "test.txt", original, 0644)
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    // When
+    err := ShredContext(ctx, "test.txt", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", context.Canceled, err)
+    }
+    content, _ := afero.ReadFile(AppFs, "test.txt")
+    if !bytes.Equal(content, original) {
+        t.Errorf("Test failed, expected file to be untouched, got:  '%q'", content)
+    }
+}
+
+func TestShredContextStopsWithCancellationCause(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    ctx, cancel := context.WithCancelCause(context.Background())
+    cancel(ErrShutdown)
+
+    // When
+    err := ShredContext(ctx, "test.txt", Options{})
+
+    // Then
+    if !errors.Is(err, ErrShutdown) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrShutdown, err)
+    }
+}
+
+func TestShredDirContextReportsCancellationCauseInResults(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    ctx, cancel := context.WithCancelCause(context.Background())
+    var once sync.Once
+    var failures []error
+    record := ResultWriterFunc(func(ctx context.Context, result Result) error {
+        if result.Err != nil {
+            failures = append(failures, result.Err)
+        }
+        return nil
+    })
+
+    // Abort as soon as the first file starts
+    opts := Options{Workers: 1, Results: record, Progress: func(ProgressEvent) {
+        once.Do(func() { cancel(ErrAborted) })
+    }}
+
+    // When
+    err := ShredDirContext(ctx, "/tree", opts)
+
+    // Then
+    if !errors.Is(err, ErrAborted) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrAborted, err)
+    }
+    if len(failures) == 0 || !errors.Is(failures[0], ErrAborted) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrAborted, failures)
+    }
+}
+
+func TestShredDirContextStopsWhenCancelled(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    ctx, cancel := context.WithCancel(context.Background())
+    var once sync.Once
+
+    // Cancel as soon as the first file starts
+    opts := Options{Remove: true, Workers: 1, Progress: func(ProgressEvent) {
+        once.Do(cancel)
+    }}
+
+    // When
+    err := ShredDirContext(ctx, "/tree", opts)
+
+    // Then
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", context.Canceled, err)
+    }
+    if exists, _ := afero.Exists(AppFs, "/tree"); !exists {
+        t.Errorf("Test failed, expected the tree to be kept")
+    }
+    untouched := 0
+    for path, original := range testTree {
+        content, _ := afero.ReadFile(AppFs, path)
+        if string(content) == original {
+            untouched++
+        }
+    }
+    if untouched < len(testTree)-1 {
+        t.Errorf("Test failed, expected at most one file to be shredded, but '%d' were", len(testTree)-untouched)
+    }
+}
+
+func TestShredDirPreScanReportsTreeTotals(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    var treeBytes int64
+    for _, content := range testTree {
+        treeBytes += 2 * int64(len(content))
+    }
+    var events []ProgressEvent
+    opts := Options{Passes: 2, Workers: 1, PreScan: true, Progress: func(event ProgressEvent) {
+        events = append(events, event)
+    }}
+
+    // When
+    err := ShredDir("/tree", opts)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    last := events[len(events)-1]
+    if last.TreeFiles != len(testTree) || last.TreeFilesDone != len(testTree)-1 {
+        t.Errorf("Test failed, expected: '%d' of '%d' files done, got:  '%d' of '%d'",
+            len(testTree)-1, len(testTree), last.TreeFilesDone, last.TreeFiles)
+    }
+    if last.TreeTotalBytes != treeBytes || last.TreeBytesWritten != treeBytes {
+        t.Errorf("Test failed, expected: '%d' of '%d' bytes, got:  '%d' of '%d'",
+            treeBytes, treeBytes, last.TreeBytesWritten, last.TreeTotalBytes)
+    }
+    if last.TreeElapsed <= 0 {
+        t.Errorf("Test failed, expected the elapsed time to be recorded, got:  '%v'", last.TreeElapsed)
+    }
+}
+
+func TestShredDirPreScanStopsWhenCancelled(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    matched := 0
+
+    // Cancel as soon as the scan looks at its first file
+    opts := Options{PreScan: true, Remove: true, Progress: func(ProgressEvent) {}}
+    opts.Match = MatcherFunc(func(path string, info os.FileInfo) bool {
+        matched++
+        cancel()
+        return true
+    })
+
+    // When
+    err := ShredDirContext(ctx, "/tree", opts)
+
+    // Then
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", context.Canceled, err)
+    }
+    if matched != 1 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 1, matched)
+    }
+    for path, content := range testTree {
+        remaining, _ := afero.ReadFile(AppFs, path)
+        if string(remaining) != content {
+            t.Errorf("Test failed for %s, expected it untouched, got:  '%s'", path, remaining)
+        }
+    }
+}
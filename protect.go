@@ -0,0 +1,52 @@
+package shredder
+
+import (
+    "fmt"
+    "github.com/spf13/afero"
+    "path/filepath"
+)
+
+// ShredDir will not shred anything inside a directory that holds a file
+// with this name, or on Linux carries an extended attribute with this
+// name, unless Options.Force is set. It is a last line of defence
+// against automation pointed at the wrong tree.
+var ProtectedMarker = ".noshred"
+var ProtectedXattr = "user.noshred"
+
+// checkProtected refuses a directory marked as protected, unless
+// opts.Force overrides the marking
+func checkProtected(dir string, opts Options) error {
+    if opts.Force {
+        return nil
+    }
+
+    protected, err := isProtected(dir)
+    if err != nil {
+        return fmt.Errorf("Error checking %s for protection: %w", dir, err)
+    }
+
+    if protected {
+        return fmt.Errorf("%w: %s", ErrProtected, dir)
+    }
+
+    return nil
+}
+
+// isProtected reports whether dir is marked as protected
+func isProtected(dir string) (bool, error) {
+    marked, err := afero.Exists(AppFs, filepath.Join(dir, ProtectedMarker))
+    if err != nil {
+        return false, wrapError(ErrStat, err)
+    }
+    if marked {
+        return true, nil
+    }
+
+    file, err := AppFs.Open(dir)
+    if err != nil {
+        return false, wrapError(ErrOpen, err)
+    }
+    defer file.Close()
+
+    return hasProtectedXattr(file), nil
+}
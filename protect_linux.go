@@ -0,0 +1,30 @@
+package shredder
+
+import (
+    "syscall"
+    "unsafe"
+)
+
+// hasProtectedXattr checks an open directory for ProtectedXattr. Files
+// without a descriptor, or on filesystems without extended attributes,
+// report false.
+func hasProtectedXattr(dir any) bool {
+    descriptor, ok := dir.(interface {
+        Fd() uintptr
+    })
+    if !ok {
+        return false
+    }
+
+    name, err := syscall.BytePtrFromString(ProtectedXattr)
+    if err != nil {
+        return false
+    }
+
+    // With no buffer, fgetxattr only reports the value's size, and fails
+    // with ENODATA if there is no such attribute
+    _, _, errno := syscall.Syscall6(syscall.SYS_FGETXATTR, descriptor.Fd(),
+        uintptr(unsafe.Pointer(name)), 0, 0, 0, 0)
+
+    return errno == 0
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package shredder
+
+// hasProtectedXattr cannot read extended attributes on this platform, so
+// only ProtectedMarker protects directories here
+func hasProtectedXattr(dir any) bool {
+    return false
+}
@@ -0,0 +1,101 @@
+package shredder
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "testing"
+)
+
+func TestShredDirLeavesProtectedDirectoriesAlone(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    afero.WriteFile(AppFs, "/tree/sub/"+ProtectedMarker, nil, 0644)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrProtected) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrProtected, err)
+    }
+    for path, content := range testTree {
+        remaining, readErr := afero.ReadFile(AppFs, path)
+        protected := path == "/tree/sub/c.txt" || path == "/tree/sub/deeper/d.db"
+        if protected && string(remaining) != content {
+            t.Errorf("Test failed for %s, expected it untouched, got:  '%s'", path, remaining)
+        }
+        if !protected && readErr == nil {
+            t.Errorf("Test failed for %s, expected it to be removed", path)
+        }
+    }
+}
+
+func TestShredDirRefusesProtectedRoot(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    afero.WriteFile(AppFs, "/tree/"+ProtectedMarker, nil, 0644)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrProtected) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrProtected, err)
+    }
+    for path, content := range testTree {
+        remaining, _ := afero.ReadFile(AppFs, path)
+        if string(remaining) != content {
+            t.Errorf("Test failed for %s, expected it untouched, got:  '%s'", path, remaining)
+        }
+    }
+}
+
+func TestShredDirWithForceShredsProtectedDirectories(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    afero.WriteFile(AppFs, "/tree/sub/"+ProtectedMarker, nil, 0644)
+
+    // When
+    err := ShredDir("/tree", Options{Remove: true, Force: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if exists, _ := afero.Exists(AppFs, "/tree"); exists {
+        t.Errorf("Test failed, expected the whole tree to be removed")
+    }
+}
+
+func TestEstimateLeavesOutProtectedDirectories(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    afero.WriteFile(AppFs, "/tree/sub/"+ProtectedMarker, nil, 0644)
+
+    // When
+    estimation, err := Estimate("/tree", Options{})
+    _, rootErr := Estimate("/tree/sub", Options{})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if estimation.Files != 2 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 2, estimation.Files)
+    }
+    if !errors.Is(rootErr, ErrProtected) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrProtected, rootErr)
+    }
+}
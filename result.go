@@ -0,0 +1,94 @@
+package shredder
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+)
+
+// A Result records the outcome of shredding one file in a batch
+type Result struct {
+    // The name the file was shredded through
+    Path string
+
+    // Other names of the same hard-linked file, shredded along with it
+    Links []string
+
+    // Why the file could not be shredded; nil on success
+    Err error
+
+    // With Verify on, the file's verification report. It is empty if the
+    // file failed before it could be read back.
+    Verification *VerificationReport
+
+    // How many passes the file was overwritten with, or zero if it failed
+    // before they were decided, and why it was given fewer than asked
+    // for: ReducedForHighEntropy, ReducedForEncryptedFilesystem, or empty
+    // if it was not
+    Passes        int
+    PassReduction string
+}
+
+// A ResultWriter receives a Result for every file ShredDir shreds, as
+// each one finishes. ShredDir never calls WriteResult concurrently, and
+// passes the context it was given, so a writer that blocks should stop
+// when it is cancelled.
+type ResultWriter interface {
+    WriteResult(ctx context.Context, result Result) error
+}
+
+// ResultWriterFunc adapts an ordinary function to a ResultWriter
+type ResultWriterFunc func(ctx context.Context, result Result) error
+
+func (f ResultWriterFunc) WriteResult(ctx context.Context, result Result) error {
+    return f(ctx, result)
+}
+
+// ChannelResultWriter sends every result on ch. Shredding waits while
+// the channel is full, so the receiver sets the pace. A receiver that
+// stops reading must cancel the context ShredDirContext was given, or
+// the shred waits for it forever; sends then fail with the context's
+// cause.
+func ChannelResultWriter(ch chan<- Result) ResultWriter {
+    return ResultWriterFunc(func(ctx context.Context, result Result) error {
+        select {
+        case ch <- result:
+            return nil
+        case <-ctx.Done():
+            return context.Cause(ctx)
+        }
+    })
+}
+
+// JSONLResultWriter writes every result to w as one line of JSON, with
+// the error, if any, as a string, and whether verification passed when
+// the file got as far as being verified:
+//
+//     {"path":"/tree/a.txt","passes":3,"verified":true}
+//     {"path":"/tree/b.zip","passes":1,"pass_reduction":"high-entropy"}
+//     {"path":"/tree/c.log","links":["/tree/d.log"],"error":"..."}
+func JSONLResultWriter(w io.Writer) ResultWriter {
+    encoder := json.NewEncoder(w)
+    return ResultWriterFunc(func(ctx context.Context, result Result) error {
+        line := struct {
+            Path          string   `json:"path"`
+            Links         []string `json:"links,omitempty"`
+            Passes        int      `json:"passes,omitempty"`
+            PassReduction string   `json:"pass_reduction,omitempty"`
+            Error         string   `json:"error,omitempty"`
+            Verified      *bool    `json:"verified,omitempty"`
+        }{Path: result.Path, Links: result.Links, Passes: result.Passes, PassReduction: result.PassReduction}
+
+        if result.Err != nil {
+            line.Error = result.Err.Error()
+        }
+
+        if result.Verification != nil && (result.Err == nil || errors.Is(result.Err, ErrVerify)) {
+            verified := result.Verification.Passed()
+            line.Verified = &verified
+        }
+
+        return encoder.Encode(line)
+    })
+}
@@ -0,0 +1,230 @@
+package shredder
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "github.com/spf13/afero"
+    "strings"
+    "testing"
+)
+
+func TestShredDirStreamsAResultPerFile(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    var paths []string
+    record := ResultWriterFunc(func(ctx context.Context, result Result) error {
+        paths = append(paths, result.Path)
+        return nil
+    })
+
+    // When
+    err := ShredDir("/tree", Options{Results: record, Workers: 4})
+
+    // Then
+    if err != nil {
+        t.Errorf("Test failed, unexpected error: %v", err)
+    }
+    if len(paths) != len(testTree) {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", len(testTree), len(paths))
+    }
+}
+
+func TestShredDirReportsFailuresThroughResults(t *testing.T) {
+    AppFs = fsThatErrorsOpening{Fs: afero.NewMemMapFs(), failing: "/tree/b.log"}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    results := make(chan Result, len(testTree))
+
+    // When
+    err := ShredDir("/tree", Options{Results: ChannelResultWriter(results)})
+    close(results)
+
+    // Then
+    if !errors.Is(err, ErrFilesFailed) || errors.Is(err, ErrOpen) {
+        t.Errorf("Test failed, expected: '%v' alone, got:  '%v'", ErrFilesFailed, err)
+    }
+
+    var failed []string
+    for result := range results {
+        if result.Err != nil {
+            failed = append(failed, result.Path)
+            if !errors.Is(result.Err, ErrOpen) {
+                t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrOpen, result.Err)
+            }
+        }
+    }
+    if len(failed) != 1 || failed[0] != "/tree/b.log" {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", []string{"/tree/b.log"}, failed)
+    }
+}
+
+func TestShredDirReturnsResultWriterErrors(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    failing := ResultWriterFunc(func(ctx context.Context, result Result) error {
+        return errors.New("Some awful result error")
+    })
+
+    // When
+    err := ShredDir("/tree", Options{Results: failing})
+
+    // Then
+    if err == nil || !strings.Contains(err.Error(), "Some awful result error") {
+        t.Errorf("Test failed, expected the result writer's error, got:  '%v'", err)
+    }
+}
+
+func TestJSONLResultWriterWritesOneLinePerResult(t *testing.T) {
+    // Given
+    var out bytes.Buffer
+    writer := JSONLResultWriter(&out)
+
+    // When
+    writer.WriteResult(context.Background(), Result{Path: "/tree/a.txt"})
+    writer.WriteResult(context.Background(), Result{Path: "/tree/b.log", Links: []string{"/tree/c.log"}, Err: ErrOpen})
+
+    // Then
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if len(lines) != 2 {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", 2, len(lines))
+    }
+
+    var decoded []map[string]any
+    for _, line := range lines {
+        var fields map[string]any
+        json.Unmarshal([]byte(line), &fields)
+        decoded = append(decoded, fields)
+    }
+    if _, ok := decoded[0]["error"]; ok {
+        t.Errorf("Test failed, expected no error field, got:  '%s'", lines[0])
+    }
+    if decoded[1]["error"] != ErrOpen.Error() {
+        t.Errorf("Test failed, expected: '%s', got:  '%v'", ErrOpen, decoded[1]["error"])
+    }
+}
+
+func TestShredDirReportsVerificationPerFile(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    var out bytes.Buffer
+    jsonl := JSONLResultWriter(&out)
+    var reports []*VerificationReport
+    record := ResultWriterFunc(func(ctx context.Context, result Result) error {
+        reports = append(reports, result.Verification)
+        return jsonl.WriteResult(ctx, result)
+    })
+
+    // When
+    err := ShredDir("/tree", Options{Verify: true, Results: record, Workers: 4})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    for _, report := range reports {
+        if report == nil || len(report.Blocks) == 0 || !report.Passed() {
+            t.Errorf("Test failed, expected a passing report for every file, got:  '%v'", report)
+        }
+    }
+    if strings.Count(out.String(), `"verified":true`) != len(testTree) {
+        t.Errorf("Test failed, expected: '%d' verified lines, got:  '%s'", len(testTree), out.String())
+    }
+}
+
+func TestShredDirRecordsReducedPasses(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    ReducePassesForHighEntropy = true
+    defer func() {
+        AppFs = afero.NewOsFs()
+        ReducePassesForHighEntropy = false
+    }()
+
+    // Given
+    afero.WriteFile(AppFs, "/tree/cipher.bin", GenerateRandomBytes(64*1024), 0644)
+    afero.WriteFile(AppFs, "/tree/plain.txt", bytes.Repeat([]byte("plain "), 1024), 0644)
+    var out bytes.Buffer
+    jsonl := JSONLResultWriter(&out)
+    results := make(map[string]Result)
+    record := ResultWriterFunc(func(ctx context.Context, result Result) error {
+        results[result.Path] = result
+        return jsonl.WriteResult(ctx, result)
+    })
+
+    // When
+    err := ShredDir("/tree", Options{Passes: 3, Results: record})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    cipher := results["/tree/cipher.bin"]
+    if cipher.Passes != HighEntropyOverwriteCount || cipher.PassReduction != ReducedForHighEntropy {
+        t.Errorf("Test failed, expected: '%d' passes for '%s', got:  '%d' for '%s'",
+            HighEntropyOverwriteCount, ReducedForHighEntropy, cipher.Passes, cipher.PassReduction)
+    }
+    plain := results["/tree/plain.txt"]
+    if plain.Passes != 3 || plain.PassReduction != "" {
+        t.Errorf("Test failed, expected: '%d' passes unreduced, got:  '%d' for '%s'", 3, plain.Passes, plain.PassReduction)
+    }
+    if !strings.Contains(out.String(), `"passes":1,"pass_reduction":"high-entropy"`) {
+        t.Errorf("Test failed, expected the reduction in the JSON lines, got:  '%s'", out.String())
+    }
+}
+
+func TestShredDirRejectsSharedVerificationReport(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+
+    // When
+    err := ShredDir("/tree", Options{Verify: true, Verification: &VerificationReport{}})
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    for path, expected := range testTree {
+        content, _ := afero.ReadFile(AppFs, path)
+        if string(content) != expected {
+            t.Errorf("Test failed, expected %s to be untouched, got:  '%s'", path, content)
+        }
+    }
+}
+
+func TestChannelResultWriterStopsWhenCancelled(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    ctx, cancel := context.WithCancel(context.Background())
+    results := make(chan Result)
+    go func() {
+        // Read one result, then stop reading and give up
+        <-results
+        cancel()
+    }()
+
+    // When
+    err := ShredDirContext(ctx, "/tree", Options{Results: ChannelResultWriter(results)})
+
+    // Then
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", context.Canceled, err)
+    }
+}
@@ -0,0 +1,98 @@
+package shredder
+
+import (
+    "context"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+)
+
+// RotateSecretFile replaces the contents of pathToFile with newContent
+// without leaving the old secret behind. The new content is written to a
+// temporary file alongside the original, synced, and renamed over it, so
+// readers only ever see the old or the new file. The old inode is held
+// open throughout and is overwritten through that handle after the
+// rename, which reaches the old bytes even once no name points at them.
+// The directory is synced between the two, so a crash cannot lose the
+// rename after the old contents are gone.
+//
+// Windows does not allow renaming over a file held open this way, so
+// there RotateSecretFile always fails with errors.ErrUnsupported,
+// before changing anything.
+func RotateSecretFile(pathToFile string, newContent []byte) error {
+    if !renameOverOpenFile {
+        return fmt.Errorf("Error rotating %s: %w", pathToFile, errors.ErrUnsupported)
+    }
+
+    if err := interrupted(context.Background()); err != nil {
+        return err
+    }
+
+    oldFile, err := AppFs.OpenFile(pathToFile, os.O_RDWR, 0644)
+    if err != nil {
+        return wrapError(ErrOpen, err)
+    }
+    defer oldFile.Close()
+
+    oldInfo, err := oldFile.Stat()
+    if err != nil {
+        return wrapError(ErrStat, err)
+    }
+
+    tempPath, err := writeSyncedTempFile(pathToFile, newContent, oldInfo.Mode().Perm())
+    if err != nil {
+        return err
+    }
+
+    err = AppFs.Rename(tempPath, pathToFile)
+    if err != nil {
+        AppFs.Remove(tempPath)
+        return fmt.Errorf("Error renaming new content into place: %w", err)
+    }
+
+    // The new content must be durably in place before the only other
+    // copy of a secret is destroyed
+    err = syncDir(filepath.Dir(pathToFile))
+    if err != nil {
+        return err
+    }
+
+    return overwriteStream(context.Background(), io.Writer(oldFile), oldInfo.Size(), Options{})
+}
+
+// writeSyncedTempFile writes content to a new, uniquely named hidden file
+// in the same directory as pathToFile, so a later rename stays on the
+// same filesystem and is atomic
+func writeSyncedTempFile(pathToFile string, content []byte, perm os.FileMode) (string, error) {
+    suffix, err := generateRandomBytes(8)
+    if err != nil {
+        return "", err
+    }
+
+    dir, base := filepath.Split(pathToFile)
+    tempPath := filepath.Join(dir, "."+base+"."+hex.EncodeToString(suffix)+".tmp")
+
+    tempFile, err := AppFs.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+    if err != nil {
+        return "", fmt.Errorf("Error creating temporary file: %w", err)
+    }
+
+    _, err = tempFile.Write(content)
+    if err == nil {
+        err = tempFile.Sync()
+    }
+    closeErr := tempFile.Close()
+    if err == nil {
+        err = closeErr
+    }
+
+    if err != nil {
+        AppFs.Remove(tempPath)
+        return "", fmt.Errorf("Error writing temporary file: %w", err)
+    }
+
+    return tempPath, nil
+}
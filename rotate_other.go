@@ -0,0 +1,7 @@
+//go:build !windows
+
+package shredder
+
+// Renaming over a file someone holds open leaves their handle on the
+// old inode
+const renameOverOpenFile = true
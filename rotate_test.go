@@ -0,0 +1,154 @@
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func skipWithoutRenameOverOpenFiles(t *testing.T) {
+    if !renameOverOpenFile {
+        t.Skip("Cannot rename over an open file on this platform")
+    }
+}
+
+func TestRotateSecretFileReplacesContent(t *testing.T) {
+    skipWithoutRenameOverOpenFiles(t)
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/secrets/token", []byte("old secret"), 0600)
+
+    // When
+    err := RotateSecretFile("/secrets/token", []byte("new secret"))
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := afero.ReadFile(AppFs, "/secrets/token")
+    if !bytes.Equal(content, []byte("new secret")) {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", "new secret", content)
+    }
+
+    // No temporary files should be left behind
+    entries, _ := afero.ReadDir(AppFs, "/secrets")
+    if len(entries) != 1 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 1, len(entries))
+    }
+}
+
+func TestRotateSecretFileKeepsPermissions(t *testing.T) {
+    skipWithoutRenameOverOpenFiles(t)
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "token", []byte("old secret"), 0600)
+
+    // When
+    RotateSecretFile("token", []byte("new secret"))
+
+    // Then
+    info, _ := AppFs.Stat("token")
+    if info.Mode().Perm() != 0600 {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", os.FileMode(0600), info.Mode().Perm())
+    }
+}
+
+func TestRotateSecretFileOverwritesOldInode(t *testing.T) {
+    skipWithoutRenameOverOpenFiles(t)
+    // Given
+    // A second hard link lets us look at the old inode after the rename
+    dir := t.TempDir()
+    secret := filepath.Join(dir, "token")
+    witness := filepath.Join(dir, "witness")
+    oldSecret := []byte("old secret that must not survive")
+    os.WriteFile(secret, oldSecret, 0600)
+    if err := os.Link(secret, witness); err != nil {
+        t.Skipf("Hard links not supported here: %v", err)
+    }
+
+    // When
+    err := RotateSecretFile(secret, []byte("new secret"))
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    oldInode, _ := os.ReadFile(witness)
+    if len(oldInode) != len(oldSecret) || bytes.Equal(oldInode, oldSecret) {
+        t.Errorf("Test failed, expected old inode to be overwritten, got:  '%x'", oldInode)
+    }
+}
+
+func TestRotateSecretFileMissingFileReturnsError(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    err := RotateSecretFile("nonexistent_file.txt", []byte("new secret"))
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    if _, statErr := AppFs.Stat("nonexistent_file.txt"); statErr == nil {
+        t.Errorf("Test failed, expected no file to be created")
+    }
+}
+
+type fsThatErrorsOnRename struct {
+    afero.Fs
+}
+
+func (fs fsThatErrorsOnRename) Rename(oldname, newname string) error {
+    return errors.New("Some awful rename error")
+}
+
+func TestRotateSecretFileRenameErrorLeavesOriginal(t *testing.T) {
+    AppFs = fsThatErrorsOnRename{afero.NewMemMapFs()}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/secrets/token", []byte("old secret"), 0600)
+
+    // When
+    err := RotateSecretFile("/secrets/token", []byte("new secret"))
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    content, _ := afero.ReadFile(AppFs, "/secrets/token")
+    if !bytes.Equal(content, []byte("old secret")) {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", "old secret", content)
+    }
+    entries, _ := afero.ReadDir(AppFs, "/secrets")
+    if len(entries) != 1 {
+        t.Errorf("Test failed, expected temporary file to be removed, found '%d' entries", len(entries))
+    }
+}
+
+func TestRotateSecretFileIsUnsupportedWithoutRenameOverOpenFiles(t *testing.T) {
+    if renameOverOpenFile {
+        t.Skip("Renaming over open files works on this platform")
+    }
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/secrets/token", []byte("old secret"), 0600)
+
+    // When
+    err := RotateSecretFile("/secrets/token", []byte("new secret"))
+
+    // Then
+    if !errors.Is(err, errors.ErrUnsupported) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", errors.ErrUnsupported, err)
+    }
+}
@@ -0,0 +1,63 @@
+//go:build unix
+
+package shredder
+
+import (
+    "github.com/spf13/afero"
+    "os"
+    "testing"
+)
+
+// Records the name of every file synced, in order
+type fsThatRecordsSyncs struct {
+    afero.Fs
+    synced *[]string
+}
+
+func (fs fsThatRecordsSyncs) Open(name string) (afero.File, error) {
+    file, err := fs.Fs.Open(name)
+    if err != nil {
+        return nil, err
+    }
+    return fileThatRecordsSyncs{File: file, synced: fs.synced}, nil
+}
+
+func (fs fsThatRecordsSyncs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    file, err := fs.Fs.OpenFile(name, flag, perm)
+    if err != nil {
+        return nil, err
+    }
+    return fileThatRecordsSyncs{File: file, synced: fs.synced}, nil
+}
+
+type fileThatRecordsSyncs struct {
+    afero.File
+    synced *[]string
+}
+
+func (f fileThatRecordsSyncs) Sync() error {
+    *f.synced = append(*f.synced, f.Name())
+    return f.File.Sync()
+}
+
+func TestRotateSecretFileSyncsDirectoryBeforeOverwriting(t *testing.T) {
+    var synced []string
+    AppFs = fsThatRecordsSyncs{Fs: afero.NewMemMapFs(), synced: &synced}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "/secrets/token", []byte("old secret"), 0600)
+    synced = nil
+
+    // When
+    err := RotateSecretFile("/secrets/token", []byte("new secret"))
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    // The temporary file, then the directory, then each overwrite pass
+    if len(synced) < 3 || synced[1] != "/secrets" || synced[2] != "/secrets/token" {
+        t.Errorf("Test failed, expected the directory synced before the old inode, got:  '%v'", synced)
+    }
+}
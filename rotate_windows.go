@@ -0,0 +1,5 @@
+package shredder
+
+// Go opens files without FILE_SHARE_DELETE, so nothing can be renamed
+// over a file while RotateSecretFile holds it open
+const renameOverOpenFile = false
@@ -0,0 +1,104 @@
+package shredder
+
+import "fmt"
+
+// A Pass produces the data written during one overwrite pass. Passes are
+// filled a chunk at a time, so Fill is told the offset of buf within the
+// pass and repeating patterns stay aligned across chunk boundaries.
+type Pass interface {
+    Fill(buf []byte, offset int64) error
+}
+
+// RandomPass fills with cryptographically random bytes
+type RandomPass struct{}
+
+func (RandomPass) Fill(buf []byte, offset int64) error {
+    return fillRandom(buf)
+}
+
+// PatternPass fills with its bytes repeated end to end
+type PatternPass []byte
+
+func (pattern PatternPass) Fill(buf []byte, offset int64) error {
+    if len(pattern) == 0 {
+        return fmt.Errorf("Error filling pass: empty pattern")
+    }
+
+    phase := int(offset % int64(len(pattern)))
+    for i := range buf {
+        buf[i] = pattern[(phase+i)%len(pattern)]
+    }
+
+    return nil
+}
+
+// A Scheme is a named sequence of passes applied in order
+type Scheme struct {
+    Name   string
+    Passes []Pass
+}
+
+// SchemeRandom overwrites with random data n times; n below zero means
+// no passes
+func SchemeRandom(n int) Scheme {
+    n = max(n, 0)
+    passes := make([]Pass, n)
+    for i := range passes {
+        passes[i] = RandomPass{}
+    }
+
+    return Scheme{Name: fmt.Sprintf("random-%d", n), Passes: passes}
+}
+
+// SchemeZero is a single pass of zeros
+var SchemeZero = Scheme{Name: "zero", Passes: []Pass{PatternPass{0x00}}}
+
+// SchemeDoD is the three-pass DoD 5220.22-M sequence: zeros, their
+// complement, then random data
+var SchemeDoD = Scheme{
+    Name:   "dod-5220.22-m",
+    Passes: []Pass{PatternPass{0x00}, PatternPass{0xFF}, RandomPass{}},
+}
+
+// SchemeGutmann is Peter Gutmann's 35-pass sequence: four random passes,
+// 27 fixed patterns aimed at old MFM/RLL encodings, and four more random
+// passes. Modern drives gain nothing over a few random passes, but some
+// policies still name it.
+var SchemeGutmann = Scheme{
+    Name: "gutmann",
+    Passes: []Pass{
+        RandomPass{}, RandomPass{}, RandomPass{}, RandomPass{},
+        PatternPass{0x55}, PatternPass{0xAA},
+        PatternPass{0x92, 0x49, 0x24}, PatternPass{0x49, 0x24, 0x92}, PatternPass{0x24, 0x92, 0x49},
+        PatternPass{0x00}, PatternPass{0x11}, PatternPass{0x22}, PatternPass{0x33},
+        PatternPass{0x44}, PatternPass{0x55}, PatternPass{0x66}, PatternPass{0x77},
+        PatternPass{0x88}, PatternPass{0x99}, PatternPass{0xAA}, PatternPass{0xBB},
+        PatternPass{0xCC}, PatternPass{0xDD}, PatternPass{0xEE}, PatternPass{0xFF},
+        PatternPass{0x92, 0x49, 0x24}, PatternPass{0x49, 0x24, 0x92}, PatternPass{0x24, 0x92, 0x49},
+        PatternPass{0x6D, 0xB6, 0xDB}, PatternPass{0xB6, 0xDB, 0x6D}, PatternPass{0xDB, 0x6D, 0xB6},
+        RandomPass{}, RandomPass{}, RandomPass{}, RandomPass{},
+    },
+}
+
+// validate fails on passes that could not be filled, so a bad scheme is
+// rejected before any of its earlier passes are written
+func (scheme Scheme) validate() error {
+    for i, pass := range scheme.Passes {
+        if pass == nil {
+            return fmt.Errorf("Error in scheme %s: pass %d is nil", scheme.Name, i+1)
+        }
+
+        if pattern, ok := pass.(PatternPass); ok && len(pattern) == 0 {
+            return fmt.Errorf("Error in scheme %s: pass %d has an empty pattern", scheme.Name, i+1)
+        }
+    }
+
+    return nil
+}
+
+// WithZeroPass returns a copy of the scheme with a final pass of zeros,
+// for policies that want wiped media to read back as blank
+func (scheme Scheme) WithZeroPass() Scheme {
+    passes := append(append([]Pass{}, scheme.Passes...), PatternPass{0x00})
+    return Scheme{Name: scheme.Name + "+zero", Passes: passes}
+}
@@ -0,0 +1,200 @@
+package shredder
+
+import (
+    "bytes"
+    "github.com/spf13/afero"
+    "testing"
+)
+
+func TestPatternPassStaysAlignedAcrossChunks(t *testing.T) {
+    // Given
+    pattern := PatternPass{0x92, 0x49, 0x24}
+    whole := make([]byte, 10)
+    first := make([]byte, 4)
+    second := make([]byte, 6)
+
+    // When
+    pattern.Fill(whole, 0)
+    pattern.Fill(first, 0)
+    pattern.Fill(second, 4)
+
+    // Then
+    chunked := append(first, second...)
+    if !bytes.Equal(whole, chunked) {
+        t.Errorf("Test failed, expected: '%x', got:  '%x'", whole, chunked)
+    }
+    expected := []byte{0x92, 0x49, 0x24, 0x92, 0x49, 0x24, 0x92, 0x49, 0x24, 0x92}
+    if !bytes.Equal(whole, expected) {
+        t.Errorf("Test failed, expected: '%x', got:  '%x'", expected, whole)
+    }
+}
+
+func TestEmptyPatternPassReturnsError(t *testing.T) {
+    // When
+    err := PatternPass{}.Fill(make([]byte, 4), 0)
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+}
+
+func TestOverwriteStreamRejectsEmptyPatternBeforeWriting(t *testing.T) {
+    // Given
+    writer := &writerThatCopiesChunks{}
+    scheme := Scheme{Name: "broken", Passes: []Pass{PatternPass{0x00}, PatternPass{}}}
+
+    // When
+    err := OverwriteStream(writer, 8, Options{Scheme: scheme})
+
+    // Then
+    if err == nil {
+        t.Errorf("Test failed, expected an error")
+    }
+    if len(writer.chunks) != 0 {
+        t.Errorf("Test failed, expected nothing written, got:  '%d' writes", len(writer.chunks))
+    }
+}
+
+func TestNegativeOverwriteCountWritesNothing(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    originalCount := ShredOverwriteCount
+    ShredOverwriteCount = -1
+    defer func() {
+        AppFs = afero.NewOsFs()
+        ShredOverwriteCount = originalCount
+    }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes"), 0644)
+
+    // When
+    err := ShredFile("test.txt", Options{})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := afero.ReadFile(AppFs, "test.txt")
+    if string(content) != "Some bytes" {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", "Some bytes", content)
+    }
+    if passes := len(SchemeRandom(-1).Passes); passes != 0 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 0, passes)
+    }
+}
+
+func TestPredefinedSchemePassCounts(t *testing.T) {
+    cases := []struct {
+        scheme   Scheme
+        expected int
+    }{
+        {SchemeRandom(5), 5},
+        {SchemeZero, 1},
+        {SchemeDoD, 3},
+        {SchemeGutmann, 35},
+        {SchemeDoD.WithZeroPass(), 4},
+    }
+
+    for _, c := range cases {
+        if len(c.scheme.Passes) != c.expected {
+            t.Errorf("Test failed for %s, expected: '%d', got:  '%d'",
+                c.scheme.Name, c.expected, len(c.scheme.Passes))
+        }
+    }
+}
+
+func TestWithZeroPassDoesNotModifyOriginal(t *testing.T) {
+    // Given
+    original := Scheme{Name: "custom", Passes: make([]Pass, 1, 4)}
+    original.Passes[0] = RandomPass{}
+
+    // When
+    extended := original.WithZeroPass()
+    original.Passes = append(original.Passes, PatternPass{0xFF})
+
+    // Then
+    last := extended.Passes[len(extended.Passes)-1]
+    if !bytes.Equal(last.(PatternPass), PatternPass{0x00}) {
+        t.Errorf("Test failed, expected: '%x', got:  '%x'", PatternPass{0x00}, last)
+    }
+}
+
+// Keeps a copy of every chunk written, since the engine reuses its buffer
+type writerThatCopiesChunks struct {
+    chunks [][]byte
+}
+
+func (w *writerThatCopiesChunks) Write(p []byte) (int, error) {
+    w.chunks = append(w.chunks, append([]byte{}, p...))
+    return len(p), nil
+}
+
+func (w *writerThatCopiesChunks) Seek(offset int64, whence int) (int64, error) {
+    return offset, nil
+}
+
+func TestOverwriteStreamAppliesSchemePassesInOrder(t *testing.T) {
+    // Given
+    writer := &writerThatCopiesChunks{}
+
+    // When
+    err := OverwriteStream(writer, 16, Options{Scheme: SchemeDoD})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if len(writer.chunks) != 3 {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", 3, len(writer.chunks))
+    }
+    if !bytes.Equal(writer.chunks[0], bytes.Repeat([]byte{0x00}, 16)) {
+        t.Errorf("Test failed, expected zeros, got:  '%x'", writer.chunks[0])
+    }
+    if !bytes.Equal(writer.chunks[1], bytes.Repeat([]byte{0xFF}, 16)) {
+        t.Errorf("Test failed, expected ones, got:  '%x'", writer.chunks[1])
+    }
+}
+
+func TestShredFileWithZeroSchemeLeavesZeros(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    testString := "Some bytes that need replacing"
+    afero.WriteFile(AppFs, "test.txt", []byte(testString), 0644)
+
+    // When
+    err := ShredFile("test.txt", Options{Scheme: SchemeRandom(2).WithZeroPass(), BufferSize: 7})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := afero.ReadFile(AppFs, "test.txt")
+    if !bytes.Equal(content, make([]byte, len(testString))) {
+        t.Errorf("Test failed, expected zeros, got:  '%x'", content)
+    }
+}
+
+func TestShredFileAppliesSchemeEvenForHighEntropyFiles(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewMemMapFs(), writes: &writes}
+    ReducePassesForHighEntropy = true
+    defer func() {
+        AppFs = afero.NewOsFs()
+        ReducePassesForHighEntropy = false
+    }()
+
+    // Given
+    afero.WriteFile(AppFs, "cipher.bin", GenerateRandomBytes(64*1024), 0644)
+    writes = 0
+
+    // When
+    ShredFile("cipher.bin", Options{Scheme: SchemeDoD})
+
+    // Then
+    if writes != 3 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 3, writes)
+    }
+}
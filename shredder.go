@@ -1,7 +1,10 @@
 package shredder
 
 import (
+    "context"
     "crypto/rand"
+    "crypto/sha256"
+    "fmt"
     "io"
     "github.com/spf13/afero"
     "os"
@@ -11,15 +14,324 @@ import (
 var AppFs = afero.NewOsFs()
 var ShredOverwriteCount = 3
 
+// Overwrites are written in chunks of this many bytes, so shredding a
+// large file does not need a buffer the size of the file. Zero or less
+// means the 1 MiB default.
+var ShredBufferSize = defaultBufferSize
+
+const defaultBufferSize = 1 << 20
+
+// Files that Inspect reports as high-entropy are already indistinguishable
+// from random data, so repeated random passes add little. This is off by
+// default; enabling it is a policy decision for the caller.
+var ReducePassesForHighEntropy = false
+var HighEntropyOverwriteCount = 1
+
+// Files under filesystem-level encryption only ever reach the disk as
+// ciphertext, and destroying the key is what really removes them, so
+// they too can be given fewer passes. They read back as plaintext, so
+// the entropy check never catches them. Also off by default.
+var ReducePassesForEncryptedFilesystem = false
+var EncryptedFilesystemOverwriteCount = 1
+
+// Why a file was given fewer passes, as recorded in its Result
+const (
+    ReducedForHighEntropy         = "high-entropy"
+    ReducedForEncryptedFilesystem = "encrypted-filesystem"
+)
+
+// Options tune a single shred operation. The zero value uses the
+// package-level defaults.
+type Options struct {
+    // The passes to overwrite with. If it has none, Passes random
+    // passes are used.
+    Scheme Scheme
+
+    // Number of random overwrite passes when no Scheme is given; zero
+    // means ShredOverwriteCount
+    Passes int
+
+    // Size of the chunks random data is written in; zero or less means
+    // ShredBufferSize
+    BufferSize int
+
+    // After overwriting, truncate the file, rename it to a random name
+    // and delete it, so neither its size nor its name survives.
+    // ShredDir also removes directories left empty.
+    Remove bool
+
+    // ShredDir only shreds files this matches; nil matches every file
+    Match Matcher
+
+    // How many files ShredDir shreds at once; zero means GOMAXPROCS
+    Workers int
+
+    // Let ShredDir shred inside directories marked as protected with
+    // ProtectedMarker or ProtectedXattr
+    Force bool
+
+    // Make ShredDir and Estimate give up on a tree that reaches more
+    // than this many levels below root, or has more than this many
+    // entries of any kind under it, so a bind-mount loop or a far larger tree
+    // than expected fails quickly. Zero means no limit.
+    MaxDepth   int
+    MaxEntries int
+
+    // After the final pass, read the range back and check every block
+    // holds what was written and none still holds its original contents.
+    // The stream must also be readable.
+    Verify bool
+
+    // Which blocks Verify reads back; the zero value reads them all
+    Sampling Sampling
+
+    // If set, filled in with the per-block results when Verify is on.
+    // ShredDir rejects it, since every file has a report of its own;
+    // each one is in that file's Result instead.
+    Verification *VerificationReport
+
+    // If set, ShredDir writes a Result here for every file rather than
+    // keeping failures in memory, and returns ErrFilesFailed with just a
+    // count if any failed. This keeps memory flat however many files
+    // there are.
+    Results ResultWriter
+
+    // Called after every chunk written. ShredDir calls it from several
+    // goroutines at once, so it must be safe for concurrent use.
+    Progress func(ProgressEvent)
+
+    // Have ShredDir walk the tree first, as Estimate does, so progress
+    // events carry totals for the whole tree and MaxDepth and MaxEntries
+    // are checked before anything is shredded. The scan stops if the
+    // context is cancelled, and if it fails nothing is shredded.
+    PreScan bool
+
+    // The file being shredded, for progress events
+    path string
+
+    // Where in the stream overwriting starts. ShredFile overwrites from
+    // here to the end of the file. Removing the file would free the bytes
+    // before it without overwriting them, so it cannot be used with
+    // Remove, ShredLinks or ShredDir's removal.
+    Offset int64
+
+    // If set, time spent in each stage of the overwrite is added to it.
+    // ShredDir times each file separately and adds them up under a lock,
+    // so it is safe to use there, but not to share between calls running
+    // at the same time.
+    Timings *StageTimings
+
+    // If set, the calls, bytes and CPU time the overwrite used are added
+    // to it. Like Timings, it is safe to use with ShredDir, which also
+    // takes its CPU time for the whole run, but not to share between
+    // calls running at the same time.
+    Usage *ResourceUsage
+
+    // Run each stage under a "shredder_stage" pprof label, so CPU
+    // profiles show which stage the time went to. The label is added to
+    // those of the context passed in, and the goroutine is left with the
+    // context's labels afterwards. Only the Context variants and ShredDir,
+    // which shreds on goroutines of its own, use it: the others have no
+    // context holding the caller's labels to put back.
+    ProfileLabels bool
+
+    // Built from the context when ProfileLabels is set
+    labels *profileLabels
+
+    // Filled in with the passes a file was given, for ShredDir's results
+    applied *appliedPasses
+}
+
+type appliedPasses struct {
+    count     int
+    reduction string
+}
+
+func (opts Options) passes() int {
+    if opts.Passes > 0 {
+        return opts.Passes
+    }
+
+    return max(ShredOverwriteCount, 0)
+}
+
+func (opts Options) scheme() Scheme {
+    if len(opts.Scheme.Passes) > 0 {
+        return opts.Scheme
+    }
+
+    return SchemeRandom(opts.passes())
+}
+
+func (opts Options) bufferSize() int {
+    if opts.BufferSize > 0 {
+        return opts.BufferSize
+    }
+
+    // A zero-length buffer would never get through a pass
+    if ShredBufferSize > 0 {
+        return ShredBufferSize
+    }
+
+    return defaultBufferSize
+}
+
+// OverwriteStream overwrites length bytes of stream, starting at
+// opts.Offset, with each pass of the chosen scheme in turn, seeking back
+// to the start of the range before each pass and after the last.
+// Failures are returned wrapping ErrRandom, ErrWrite, ErrSync or ErrSeek,
+// a stream opened in append mode is rejected with ErrAppendModeHandle, and
+// a negative length or offset with ErrInvalidRange. A scheme with a nil
+// pass or an empty pattern is rejected before anything is written.
+func OverwriteStream(stream io.WriteSeeker, length int64, opts Options) error {
+    opts.ProfileLabels = false
+    return overwriteStream(context.Background(), stream, length, opts)
+}
+
+// OverwriteStreamContext is OverwriteStream, stopping with the context's
+// cause if it is cancelled: context.Canceled or context.DeadlineExceeded,
+// or the error given to context.WithCancelCause, such as ErrShutdown.
+// Cancellation is checked before every chunk, so a cancelled overwrite
+// leaves the current pass partly written.
+func OverwriteStreamContext(ctx context.Context, stream io.WriteSeeker, length int64, opts Options) error {
+    return overwriteStream(ctx, stream, length, opts)
+}
+
+// OverwriteStreamWithRandomBytes is the panicking form of OverwriteStream
+// using the default options. It accepts any writer, and panics if the
+// writer cannot seek.
 func OverwriteStreamWithRandomBytes(writer io.Writer, length int64) {
-    for i := 0; i < ShredOverwriteCount; i++ {
-        randomBytes := GenerateRandomBytes(length)
+    err := overwriteStream(context.Background(), writer, length, Options{})
+    if err != nil {
+        panic(err.Error())
+    }
+}
+
+func overwriteStream(ctx context.Context, writer io.Writer, length int64, opts Options) error {
+    if length < 0 || opts.Offset < 0 {
+        return fmt.Errorf("%w: length %d and offset %d must not be negative", ErrInvalidRange, length, opts.Offset)
+    }
+
+    scheme := opts.scheme()
+    err := scheme.validate()
+    if err != nil {
+        return err
+    }
+
+    // We need to return to the start of the range for every pass, so
+    // fail before writing anything if that's not supported
+    seeker, ok := writer.(io.Seeker)
+    if !ok {
+        return ErrNotSeekable
+    }
+
+    // Writes to an append-mode handle always land at the end of the
+    // file whatever the seek position, so they would never overwrite
+    // anything
+    if isAppendMode(writer) {
+        return ErrAppendModeHandle
+    }
 
-        // Write the random bytes to the stream
-        _, err := writer.Write(randomBytes)
+    if opts.ProfileLabels {
+        opts.labels = newProfileLabels(ctx)
+    }
 
+    rewind := func() error {
+        _, err := seeker.Seek(opts.Offset, io.SeekStart)
         if err != nil {
-            panic("Error writing random bytes to stream: " + err.Error())
+            return wrapError(ErrSeek, err)
+        }
+        return nil
+    }
+
+    err = rewind()
+    if err != nil {
+        return err
+    }
+
+    // One buffer is reused for every chunk of every pass, so memory use
+    // stays the same however large the stream is
+    buffer := make([]byte, min(int64(opts.bufferSize()), length))
+
+    if opts.Usage != nil {
+        opts.Usage.PeakBufferBytes = max(opts.Usage.PeakBufferBytes, int64(len(buffer)))
+        defer opts.Usage.addCPUSince(processCPUTime())
+    }
+
+    // Fingerprint the original contents before they are destroyed, so
+    // verification can prove none of them survived
+    var originalHashes [][sha256.Size]byte
+    var selected []bool
+    var sampling Sampling
+    if opts.Verify {
+        blocks := 0
+        if length > 0 {
+            blocks = int((length + int64(len(buffer)) - 1) / int64(len(buffer)))
+        }
+
+        selected, sampling, err = opts.Sampling.choose(blocks)
+        if err != nil {
+            return err
+        }
+
+        originalHashes, err = readBlockHashes(ctx, writer, length, buffer, selected, opts)
+        if err != nil {
+            return err
+        }
+
+        err = rewind()
+        if err != nil {
+            return err
+        }
+    }
+
+    passes := scheme.Passes
+    var writtenHashes [][sha256.Size]byte
+    progress := ProgressEvent{
+        Path:        opts.path,
+        TotalPasses: len(passes),
+        TotalBytes:  length * int64(len(passes)),
+    }
+
+    for i, pass := range passes {
+        progress.Pass = i + 1
+        progress.PassBytesWritten = 0
+
+        for written := int64(0); written < length; {
+            if err := interrupted(ctx); err != nil {
+                return err
+            }
+
+            chunk := buffer[:min(int64(len(buffer)), length-written)]
+
+            start := opts.beginStage(StageGenerate)
+            err := pass.Fill(chunk, written)
+            opts.endStage(StageGenerate, start)
+
+            if err != nil {
+                return err
+            }
+
+            // Write the pass data to the stream
+            start = opts.beginStage(StageWrite)
+            err = writeFull(writer, chunk, opts.Usage)
+            opts.endStage(StageWrite, start)
+
+            if err != nil {
+                return err
+            }
+
+            written += int64(len(chunk))
+
+            if opts.Verify && i == len(passes)-1 {
+                writtenHashes = append(writtenHashes, sha256.Sum256(chunk))
+            }
+
+            if opts.Progress != nil {
+                progress.PassBytesWritten = written
+                progress.BytesWritten += int64(len(chunk))
+                opts.Progress(progress)
+            }
         }
 
         // Sync the writer to ensure the data is written
@@ -27,37 +339,109 @@ func OverwriteStreamWithRandomBytes(writer io.Writer, length int64) {
         if syncer, ok := writer.(interface {
             Sync() error
         }); ok {
+            start := opts.beginStage(StageSync)
             syncErr := syncer.Sync()
+            opts.endStage(StageSync, start)
+
             if syncErr != nil {
-                panic("Error syncing writer: " + syncErr.Error())
+                return wrapError(ErrSync, syncErr)
+            }
+
+            if opts.Usage != nil {
+                opts.Usage.Syncs++
+                opts.Usage.BytesSynced += length
             }
         }
 
-        // Seek to the beginning of the stream - we do need
-        // to do this, so panic if it's not supported
-        if seeker, ok := writer.(interface {
-            Seek(offset int64, whence int) (int64, error)
-        }); ok {
-            _, seekErr := seeker.Seek(0, 0)
-            if seekErr != nil {
-                panic("Error seeking writer: " + seekErr.Error())
-            } 
-        } else {
-            panic("Writer does not support seeking")
+        // Seek back to the start of the range for the next pass
+        err := rewind()
+        if err != nil {
+            return err
         }
     }
+
+    if !opts.Verify {
+        return nil
+    }
+
+    report, err := verifyBlocks(ctx, writer, length, buffer, selected, originalHashes, writtenHashes, opts)
+    if err != nil {
+        return err
+    }
+    report.Sampling = sampling
+
+    if opts.Verification != nil {
+        *opts.Verification = report
+    }
+
+    err = rewind()
+    if err != nil {
+        return err
+    }
+
+    if !report.Passed() {
+        return fmt.Errorf("%w: %d of %d blocks failed", ErrVerify, len(report.Failures()), len(report.Blocks))
+    }
+
+    return nil
+}
+
+// writeFull writes all of chunk, carrying on after short writes. A
+// writer that stops making progress without an error would otherwise
+// leave part of the pass unwritten, so that fails with io.ErrShortWrite.
+// Calls and bytes are counted in usage if it is set.
+func writeFull(writer io.Writer, chunk []byte, usage *ResourceUsage) error {
+    for len(chunk) > 0 {
+        n, err := writer.Write(chunk)
+        if usage != nil {
+            usage.Writes++
+            usage.BytesWritten += int64(max(n, 0))
+        }
+
+        if err != nil {
+            return wrapError(ErrWrite, err)
+        }
+
+        if n <= 0 || n > len(chunk) {
+            return wrapError(ErrWrite, io.ErrShortWrite)
+        }
+
+        chunk = chunk[n:]
+    }
+
+    return nil
 }
 
 func GenerateRandomBytes(length int64) []byte {
+    randomBytes, err := generateRandomBytes(length)
+    if err != nil {
+        panic(err.Error())
+    }
+
+    return randomBytes
+}
+
+func generateRandomBytes(length int64) ([]byte, error) {
     randomBytes := make([]byte, length)
 
-    _, err := rand.Read(randomBytes)
+    err := fillRandom(randomBytes)
+    if err != nil {
+        return nil, err
+    }
+
+    return randomBytes, nil
+}
+
+func fillRandom(buffer []byte) error {
+    // Read through rand.Reader rather than rand.Read, which treats a
+    // failing reader as fatal and would bypass our error handling
+    _, err := io.ReadFull(rand.Reader, buffer)
 
     if err != nil {
-        panic("Error generating random bytes: " + err.Error())
+        return wrapError(ErrRandom, err)
     }
 
-    return randomBytes
+    return nil
 }
 
 func GetFileLength(file afero.File) int64 {
@@ -69,20 +453,138 @@ func GetFileLength(file afero.File) int64 {
     return fileInfo.Size()
 }
 
-func Shred(pathToFile string) {
+// ShredFile overwrites the file at pathToFile in place, and with
+// opts.Remove also truncates, renames and deletes it. Failures are
+// returned wrapping ErrOpen, ErrStat, ErrRemove or one of the
+// OverwriteStream errors, and opts.Remove with a nonzero opts.Offset is
+// rejected with ErrInvalidRange.
+func ShredFile(pathToFile string, opts Options) error {
+    opts.ProfileLabels = false
+    return ShredContext(context.Background(), pathToFile, opts)
+}
+
+// ShredContext is ShredFile, stopping with the context's cause if it is
+// cancelled before the file is fully overwritten. A cancelled file is
+// never removed.
+func ShredContext(ctx context.Context, pathToFile string, opts Options) error {
+    if opts.Remove {
+        err := checkRemovable(opts)
+        if err != nil {
+            return err
+        }
+    }
+
+    opts.path = pathToFile
+    err := overwriteFile(ctx, pathToFile, opts)
+    if err != nil || !opts.Remove {
+        return err
+    }
+
+    return removeShredded(pathToFile)
+}
+
+// checkRemovable refuses to remove a file that is only overwritten from
+// opts.Offset on, as truncating it would free the bytes before the
+// offset with their contents intact
+func checkRemovable(opts Options) error {
+    if opts.Offset != 0 {
+        return fmt.Errorf("%w: cannot remove a file overwritten from offset %d", ErrInvalidRange, opts.Offset)
+    }
+
+    return nil
+}
+
+func overwriteFile(ctx context.Context, pathToFile string, opts Options) error {
     file, err := AppFs.OpenFile(pathToFile, os.O_RDWR, 0644)
 
     if err != nil {
-        panic("Error opening file: " + err.Error())
+        return wrapError(ErrOpen, err)
     }
 
     // Now we know the file exists and is open, we can defer
     // the close and make sure it gets closed regardless of errors
     defer file.Close()
 
-    fileLength := GetFileLength(file)
+    fileInfo, err := file.Stat()
+    if err != nil {
+        return wrapError(ErrStat, err)
+    }
+
+    // A device cannot be truncated, renamed or deleted like a file, so
+    // fail before overwriting it rather than after
+    if opts.Remove && fileInfo.Mode()&os.ModeDevice != 0 {
+        return fmt.Errorf("%w: %s is a device", ErrRemove, pathToFile)
+    }
+
+    passes, reduction, err := passesFor(pathToFile, fileInfo, opts)
+    if err != nil {
+        return err
+    }
+
+    size, err := targetSize(file, fileInfo)
+    if err != nil {
+        return err
+    }
+
+    opts.Passes = passes
+    if opts.applied != nil {
+        *opts.applied = appliedPasses{count: len(opts.scheme().Passes), reduction: reduction}
+    }
+
     fileStream := io.Writer(file)
-    OverwriteStreamWithRandomBytes(fileStream, fileLength)
+    return overwriteStream(ctx, fileStream, max(size-opts.Offset, 0), opts)
 }
 
+// targetSize is the number of bytes there are to overwrite in file.
+// Devices stat as empty, so their size comes from seeking to the end.
+func targetSize(file afero.File, info os.FileInfo) (int64, error) {
+    if info.Mode()&os.ModeDevice == 0 {
+        return info.Size(), nil
+    }
+
+    size, err := file.Seek(0, io.SeekEnd)
+    if err != nil {
+        return 0, wrapError(ErrSeek, err)
+    }
+
+    return size, nil
+}
 
+// Shred is the panicking form of ShredFile using the default options
+func Shred(pathToFile string) {
+    err := ShredFile(pathToFile, Options{})
+    if err != nil {
+        panic(err.Error())
+    }
+}
+
+// passesFor decides how many random overwrite passes a file needs, and
+// why, if it is fewer than asked for. An explicit scheme is always
+// applied in full, and devices, which Inspect cannot read, always get
+// every pass.
+func passesFor(pathToFile string, info os.FileInfo, opts Options) (int, string, error) {
+    passes := opts.passes()
+    reduceHighEntropy := ReducePassesForHighEntropy && HighEntropyOverwriteCount < passes
+    reduceEncrypted := ReducePassesForEncryptedFilesystem && EncryptedFilesystemOverwriteCount < passes
+    if !(reduceHighEntropy || reduceEncrypted) || len(opts.Scheme.Passes) > 0 || !info.Mode().IsRegular() {
+        return passes, "", nil
+    }
+
+    inspection, err := Inspect(pathToFile)
+    if err != nil {
+        return 0, "", err
+    }
+
+    reduction := ""
+    if reduceHighEntropy && inspection.HighEntropy {
+        passes = HighEntropyOverwriteCount
+        reduction = ReducedForHighEntropy
+    }
+
+    if reduceEncrypted && inspection.FilesystemEncrypted && EncryptedFilesystemOverwriteCount < passes {
+        passes = EncryptedFilesystemOverwriteCount
+        reduction = ReducedForEncryptedFilesystem
+    }
+
+    return passes, reduction, nil
+}
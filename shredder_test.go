@@ -3,11 +3,18 @@ package shredder
 import (
     "testing"
     "bytes"
+    "context"
     "reflect"
+    "runtime"
+    "shredder/faultfs"
+    "syscall"
     "errors"
+    "io"
     "crypto/rand"
     "github.com/spf13/afero"
     "os"
+    "path/filepath"
+    "time"
 )
 
 func TestGenerateRandomBytes(t *testing.T) {
@@ -351,3 +358,604 @@ func TestGetFileLengthWithValidFile(t *testing.T) {
     AppFs = afero.NewOsFs()
 }
 
+
+type fsThatRecordsWrites struct {
+    afero.Fs
+    writes *int
+}
+
+func (fs fsThatRecordsWrites) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    file, err := fs.Fs.OpenFile(name, flag, perm)
+    return fileThatRecordsWrites{File: file, writes: fs.writes}, err
+}
+
+func (fs fsThatRecordsWrites) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+    return lstatThrough(fs.Fs, name)
+}
+
+type fileThatRecordsWrites struct {
+    afero.File
+    writes *int
+}
+
+func (f fileThatRecordsWrites) Write(p []byte) (int, error) {
+    *f.writes++
+    return f.File.Write(p)
+}
+
+func TestShredReducesPassesForHighEntropyFilesWhenEnabled(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewMemMapFs(), writes: &writes}
+    ReducePassesForHighEntropy = true
+    defer func() {
+        AppFs = afero.NewOsFs()
+        ReducePassesForHighEntropy = false
+    }()
+
+    // Given
+    afero.WriteFile(AppFs, "cipher.bin", GenerateRandomBytes(64*1024), 0644)
+    afero.WriteFile(AppFs, "plain.txt", bytes.Repeat([]byte("plain "), 1024), 0644)
+    writes = 0
+
+    // When
+    Shred("cipher.bin")
+
+    // Then
+    if writes != HighEntropyOverwriteCount {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", HighEntropyOverwriteCount, writes)
+    }
+
+    // When
+    writes = 0
+    Shred("plain.txt")
+
+    // Then
+    if writes != ShredOverwriteCount {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", ShredOverwriteCount, writes)
+    }
+}
+
+func TestShredReducesPassesForEncryptedFilesystemWhenEnabled(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewMemMapFs(), writes: &writes}
+    ReducePassesForEncryptedFilesystem = true
+    filesystemEncrypted = func(file any, info os.FileInfo) bool {
+        return info.Name() == "fscrypt.txt"
+    }
+    defer func() {
+        AppFs = afero.NewOsFs()
+        ReducePassesForEncryptedFilesystem = false
+        filesystemEncrypted = isFilesystemEncrypted
+    }()
+
+    // Given
+    afero.WriteFile(AppFs, "fscrypt.txt", bytes.Repeat([]byte("plain "), 1024), 0644)
+    afero.WriteFile(AppFs, "plain.txt", bytes.Repeat([]byte("plain "), 1024), 0644)
+    writes = 0
+
+    // When
+    Shred("fscrypt.txt")
+
+    // Then
+    if writes != EncryptedFilesystemOverwriteCount {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", EncryptedFilesystemOverwriteCount, writes)
+    }
+
+    // When
+    writes = 0
+    Shred("plain.txt")
+
+    // Then
+    if writes != ShredOverwriteCount {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", ShredOverwriteCount, writes)
+    }
+}
+
+func TestShredUsesAllPassesForHighEntropyFilesByDefault(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewMemMapFs(), writes: &writes}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "cipher.bin", GenerateRandomBytes(64*1024), 0644)
+    writes = 0
+
+    // When
+    Shred("cipher.bin")
+
+    // Then
+    if writes != ShredOverwriteCount {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", ShredOverwriteCount, writes)
+    }
+}
+
+func TestShredFileMissingFileReturnsErrOpen(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    err := ShredFile("nonexistent_file.txt", Options{})
+
+    // Then
+    if !errors.Is(err, ErrOpen) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrOpen, err)
+    }
+    if !errors.Is(err, os.ErrNotExist) {
+        t.Errorf("Test failed, expected underlying cause to be kept, got:  '%v'", err)
+    }
+}
+
+func TestShredFileOverwritesFile(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    testString := "Some bytes that need replacing"
+    afero.WriteFile(AppFs, "test.txt", []byte(testString), 0644)
+
+    // When
+    err := ShredFile("test.txt", Options{})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    buffer, _ := afero.ReadFile(AppFs, "test.txt")
+    if len(buffer) != len(testString) || bytes.Equal(buffer, []byte(testString)) {
+        t.Errorf("Test failed, expected different bytes of the same length, got:  '%x'", buffer)
+    }
+}
+
+func TestShredFileUsesPassesOption(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewMemMapFs(), writes: &writes}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    writes = 0
+
+    // When
+    ShredFile("test.txt", Options{Passes: 7})
+
+    // Then
+    if writes != 7 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 7, writes)
+    }
+}
+
+func TestOverwriteStreamReturnsTypedErrors(t *testing.T) {
+    cases := []struct {
+        name     string
+        stream   io.WriteSeeker
+        expected error
+    }{
+        {"write", &seekerThatErrorsOnWrite{}, ErrWrite},
+        {"sync", &seekerThatErrorsOnSync{buf: &bytes.Buffer{}}, ErrSync},
+        {"seek", &WriterThatErrorsOnSeek{buf: &bytes.Buffer{}}, ErrSeek},
+    }
+
+    for _, c := range cases {
+        // When
+        err := OverwriteStream(c.stream, 6, Options{})
+
+        // Then
+        if !errors.Is(err, c.expected) {
+            t.Errorf("Test failed for %s, expected: '%v', got:  '%v'", c.name, c.expected, err)
+        }
+    }
+}
+
+func TestRemovingRejectsAnOffset(t *testing.T) {
+    cases := []struct {
+        name  string
+        shred func(path string, opts Options) error
+    }{
+        {"ShredFile", ShredFile},
+        {"ShredLinks", func(path string, opts Options) error { return ShredLinks([]string{path}, opts) }},
+        {"ShredDir", func(path string, opts Options) error { return ShredDir(filepath.Dir(path), opts) }},
+    }
+
+    for _, c := range cases {
+        AppFs = afero.NewMemMapFs()
+
+        // Given
+        path := "/tree/a.txt"
+        afero.WriteFile(AppFs, path, []byte("Some bytes that need replacing"), 0644)
+
+        // When
+        err := c.shred(path, Options{Offset: 5, Remove: true})
+
+        // Then
+        if !errors.Is(err, ErrInvalidRange) {
+            t.Errorf("Test failed for %s, expected: '%v', got:  '%v'", c.name, ErrInvalidRange, err)
+        }
+        content, _ := afero.ReadFile(AppFs, path)
+        if string(content) != "Some bytes that need replacing" {
+            t.Errorf("Test failed for %s, expected the file untouched, got:  '%s'", c.name, content)
+        }
+    }
+
+    AppFs = afero.NewOsFs()
+}
+
+func TestOverwriteStreamRejectsNegativeRanges(t *testing.T) {
+    cases := []struct {
+        name   string
+        length int64
+        offset int64
+    }{
+        {"length", -1, 0},
+        {"offset", 6, -1},
+    }
+
+    for _, c := range cases {
+        // Given
+        writer := &WriterThatRecordsBytesWritten{buf: &bytes.Buffer{}, bytesWritten: [][]byte{}}
+
+        // When
+        err := OverwriteStream(writer, c.length, Options{Offset: c.offset})
+
+        // Then
+        if !errors.Is(err, ErrInvalidRange) {
+            t.Errorf("Test failed for %s, expected: '%v', got:  '%v'", c.name, ErrInvalidRange, err)
+        }
+        if len(writer.bytesWritten) != 0 {
+            t.Errorf("Test failed for %s, expected nothing written, got:  '%d' writes", c.name, len(writer.bytesWritten))
+        }
+    }
+}
+
+type seekerThatErrorsOnWrite struct {
+    WriterThatErrorsOnWrite
+}
+
+func (w *seekerThatErrorsOnWrite) Seek(offset int64, whence int) (int64, error) {
+    return 0, nil
+}
+
+type seekerThatErrorsOnSync struct {
+    buf *bytes.Buffer
+}
+
+func (w *seekerThatErrorsOnSync) Write(p []byte) (n int, err error) {
+    return w.buf.Write(p)
+}
+
+func (w *seekerThatErrorsOnSync) Sync() error {
+    return errors.New("Some awful sync error")
+}
+
+func (w *seekerThatErrorsOnSync) Seek(offset int64, whence int) (int64, error) {
+    return 0, nil
+}
+
+func TestOverwriteStreamRandErrorReturnsErrRandom(t *testing.T) {
+    // Given
+    originalRandReader := rand.Reader
+    rand.Reader = RandReaderThatErrors{}
+    defer func() { rand.Reader = originalRandReader }()
+
+    // When
+    err := OverwriteStream(&WriterThatDoesNotImplementSync{buf: &bytes.Buffer{}}, 6, Options{})
+
+    // Then
+    if !errors.Is(err, ErrRandom) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrRandom, err)
+    }
+}
+
+func TestOverwriteWithoutSeekReturnsErrNotSeekable(t *testing.T) {
+    // Given
+    writer := &WriterThatDoesNotImplementSeek{buf: &bytes.Buffer{}}
+
+    // When
+    err := overwriteStream(context.Background(), writer, 6, Options{Passes: 1})
+
+    // Then
+    if !errors.Is(err, ErrNotSeekable) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrNotSeekable, err)
+    }
+}
+
+func TestOverwriteStreamWritesInChunks(t *testing.T) {
+    // Given
+    writer := &WriterThatRecordsBytesWritten{buf: &bytes.Buffer{}, bytesWritten: [][]byte{}}
+
+    // When
+    err := OverwriteStream(writer, 30, Options{Passes: 2, BufferSize: 8})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    expectedSizes := []int{8, 8, 8, 6, 8, 8, 8, 6}
+    if len(writer.bytesWritten) != len(expectedSizes) {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", len(expectedSizes), len(writer.bytesWritten))
+    }
+    for i, chunk := range writer.bytesWritten {
+        if len(chunk) != expectedSizes[i] {
+            t.Errorf("Test failed for chunk %d, expected: '%d', got:  '%d'", i, expectedSizes[i], len(chunk))
+        }
+    }
+    if writer.buf.Len() != 60 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 60, writer.buf.Len())
+    }
+}
+
+func TestOverwriteStreamUsesShredBufferSizeByDefault(t *testing.T) {
+    // Given
+    originalBufferSize := ShredBufferSize
+    ShredBufferSize = 10
+    defer func() { ShredBufferSize = originalBufferSize }()
+    writer := &WriterThatRecordsBytesWritten{buf: &bytes.Buffer{}, bytesWritten: [][]byte{}}
+
+    // When
+    OverwriteStream(writer, 30, Options{Passes: 1})
+
+    // Then
+    if len(writer.bytesWritten) != 3 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 3, len(writer.bytesWritten))
+    }
+}
+
+func TestOverwriteStreamUsesDefaultForUnusableShredBufferSize(t *testing.T) {
+    cases := []int{0, -1}
+
+    for _, bufferSize := range cases {
+        // Given
+        originalBufferSize := ShredBufferSize
+        ShredBufferSize = bufferSize
+        writer := &WriterThatRecordsBytesWritten{buf: &bytes.Buffer{}, bytesWritten: [][]byte{}}
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+        // When
+        err := OverwriteStreamContext(ctx, writer, 30, Options{Passes: 1, BufferSize: bufferSize})
+        cancel()
+        ShredBufferSize = originalBufferSize
+
+        // Then
+        if err != nil {
+            t.Fatalf("Test failed for %d, unexpected error: %v", bufferSize, err)
+        }
+        if len(writer.bytesWritten) != 1 || writer.buf.Len() != 30 {
+            t.Errorf("Test failed for %d, expected: '%d', got:  '%d'", bufferSize, 30, writer.buf.Len())
+        }
+    }
+}
+
+// Discards everything written, standing in for a very large file
+type discardingSeeker struct{}
+
+func (discardingSeeker) Write(p []byte) (int, error) {
+    return len(p), nil
+}
+
+func (discardingSeeker) Seek(offset int64, whence int) (int64, error) {
+    return 0, nil
+}
+
+func TestOverwriteStreamMemoryDoesNotGrowWithLength(t *testing.T) {
+    // Given
+    var length int64 = 64 << 20
+    opts := Options{Passes: 1, BufferSize: 64 << 10}
+
+    // When
+    var before, after runtime.MemStats
+    runtime.ReadMemStats(&before)
+    err := OverwriteStream(discardingSeeker{}, length, opts)
+    runtime.ReadMemStats(&after)
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    allocated := after.TotalAlloc - before.TotalAlloc
+    if allocated > 1<<20 {
+        t.Errorf("Test failed, expected under '%d' bytes allocated, got:  '%d'", 1<<20, allocated)
+    }
+}
+
+func benchmarkOverwriteStream(b *testing.B, length int64) {
+    b.ReportAllocs()
+    b.SetBytes(length)
+
+    for i := 0; i < b.N; i++ {
+        OverwriteStream(discardingSeeker{}, length, Options{Passes: 1})
+    }
+}
+
+// Bytes allocated per operation should stay at one buffer's worth from
+// 1 MiB upwards, rather than tracking the stream length
+func BenchmarkOverwriteStream1MiB(b *testing.B)   { benchmarkOverwriteStream(b, 1<<20) }
+func BenchmarkOverwriteStream16MiB(b *testing.B)  { benchmarkOverwriteStream(b, 16<<20) }
+func BenchmarkOverwriteStream128MiB(b *testing.B) { benchmarkOverwriteStream(b, 128<<20) }
+
+func TestOverwriteStreamRejectsAppendModeFiles(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("Append mode cannot be detected on this platform")
+    }
+
+    // Given
+    path := filepath.Join(t.TempDir(), "appending.txt")
+    original := []byte("Some bytes that need replacing")
+    os.WriteFile(path, original, 0644)
+    file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+    defer file.Close()
+
+    // When
+    err := OverwriteStream(file, int64(len(original)), Options{})
+
+    // Then
+    if !errors.Is(err, ErrAppendModeHandle) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrAppendModeHandle, err)
+    }
+    content, _ := os.ReadFile(path)
+    if !bytes.Equal(content, original) {
+        t.Errorf("Test failed, expected file to be untouched, got:  '%x'", content)
+    }
+}
+
+func TestOverwriteStreamStartsAtOffset(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    original := []byte("keep this|replace this")
+    afero.WriteFile(AppFs, "test.txt", original, 0644)
+    file, _ := AppFs.OpenFile("test.txt", os.O_RDWR, 0644)
+
+    // When
+    err := OverwriteStream(file, 12, Options{Offset: 10})
+    file.Close()
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := afero.ReadFile(AppFs, "test.txt")
+    if len(content) != len(original) {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", len(original), len(content))
+    }
+    if !bytes.Equal(content[:10], original[:10]) {
+        t.Errorf("Test failed, expected: '%s', got:  '%s'", original[:10], content[:10])
+    }
+    if bytes.Equal(content[10:], original[10:]) {
+        t.Errorf("Test failed, expected bytes after the offset to differ")
+    }
+}
+
+func TestShredFileFromOffsetToEnd(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    original := []byte("header|Some bytes that need replacing")
+    afero.WriteFile(AppFs, "test.txt", original, 0644)
+
+    // When
+    err := ShredFile("test.txt", Options{Offset: 7})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    content, _ := afero.ReadFile(AppFs, "test.txt")
+    if len(content) != len(original) || !bytes.Equal(content[:7], original[:7]) {
+        t.Errorf("Test failed, expected header to survive, got:  '%q'", content)
+    }
+    if bytes.Equal(content[7:], original[7:]) {
+        t.Errorf("Test failed, expected bytes after the offset to differ")
+    }
+}
+
+// Records every seek it is asked to make
+type seekRecordingWriter struct {
+    seeks [][2]int64
+}
+
+func (w *seekRecordingWriter) Write(p []byte) (int, error) {
+    return len(p), nil
+}
+
+func (w *seekRecordingWriter) Seek(offset int64, whence int) (int64, error) {
+    w.seeks = append(w.seeks, [2]int64{offset, int64(whence)})
+    return offset, nil
+}
+
+func TestOverwriteStreamSeeksToRangeStartBeforeEachPass(t *testing.T) {
+    // Given
+    writer := &seekRecordingWriter{}
+
+    // When
+    OverwriteStream(writer, 8, Options{Passes: 2, Offset: 5})
+
+    // Then
+    // Once before the first pass, then once after each pass
+    if len(writer.seeks) != 3 {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", 3, len(writer.seeks))
+    }
+    for _, seek := range writer.seeks {
+        if seek != [2]int64{5, io.SeekStart} {
+            t.Errorf("Test failed, expected: '%v', got:  '%v'", [2]int64{5, io.SeekStart}, seek)
+        }
+    }
+}
+
+func TestShredFileSurfacesInjectedFaults(t *testing.T) {
+    cases := []struct {
+        name     string
+        faults   faultfs.Faults
+        expected []error
+    }{
+        {"failed write on a later pass", faultfs.Faults{FailWrite: 2}, []error{ErrWrite, faultfs.ErrInjected}},
+        {"interrupted write", faultfs.Faults{InterruptWrite: 1}, []error{ErrWrite, syscall.EINTR}},
+        {"disk full", faultfs.Faults{DiskSize: 10}, []error{ErrWrite, syscall.ENOSPC}},
+        {"failed sync", faultfs.Faults{FailSync: 3}, []error{ErrSync, faultfs.ErrInjected}},
+    }
+
+    for _, c := range cases {
+        memFs := afero.NewMemMapFs()
+        afero.WriteFile(memFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+        AppFs = faultfs.New(memFs, c.faults)
+
+        // When
+        err := ShredFile("test.txt", Options{})
+
+        // Then
+        for _, expected := range c.expected {
+            if !errors.Is(err, expected) {
+                t.Errorf("Test failed for %s, expected: '%v', got:  '%v'", c.name, expected, err)
+            }
+        }
+    }
+
+    AppFs = afero.NewOsFs()
+}
+
+func TestShredFileCompletesChunksAfterShortWrites(t *testing.T) {
+    // Given
+    memFs := afero.NewMemMapFs()
+    testString := "Some bytes that need replacing"
+    afero.WriteFile(memFs, "test.txt", []byte(testString), 0644)
+    fs := faultfs.New(memFs, faultfs.Faults{MaxWrite: 7})
+    AppFs = fs
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    err := ShredFile("test.txt", Options{Scheme: SchemeZero, Verify: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    // The 30 byte pass takes five writes of at most 7 bytes
+    if fs.Writes() != 5 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 5, fs.Writes())
+    }
+    content, _ := afero.ReadFile(memFs, "test.txt")
+    if !bytes.Equal(content, make([]byte, len(testString))) {
+        t.Errorf("Test failed, expected zeros, got:  '%x'", content)
+    }
+}
+
+// Accepts nothing but never reports an error
+type writerThatMakesNoProgress struct{}
+
+func (writerThatMakesNoProgress) Write(p []byte) (int, error) {
+    return 0, nil
+}
+
+func (writerThatMakesNoProgress) Seek(offset int64, whence int) (int64, error) {
+    return offset, nil
+}
+
+func TestOverwriteStreamWithStalledWriterReturnsErrShortWrite(t *testing.T) {
+    // When
+    err := OverwriteStream(writerThatMakesNoProgress{}, 8, Options{})
+
+    // Then
+    if !errors.Is(err, ErrWrite) || !errors.Is(err, io.ErrShortWrite) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", io.ErrShortWrite, err)
+    }
+}
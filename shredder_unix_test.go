@@ -0,0 +1,48 @@
+//go:build unix
+
+package shredder
+
+import (
+    "errors"
+    "github.com/spf13/afero"
+    "testing"
+)
+
+func TestPassesForGivesDevicesEveryPass(t *testing.T) {
+    ReducePassesForHighEntropy, ReducePassesForEncryptedFilesystem = true, true
+    defer func() { ReducePassesForHighEntropy, ReducePassesForEncryptedFilesystem = false, false }()
+
+    // Given
+    info, err := AppFs.Stat("/dev/null")
+    if err != nil {
+        t.Fatalf("Could not stat /dev/null: %v", err)
+    }
+
+    // When
+    passes, _, err := passesFor("/dev/null", info, Options{Passes: 3})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if passes != 3 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 3, passes)
+    }
+}
+
+func TestShredFileRejectsRemovingDevicesBeforeWriting(t *testing.T) {
+    writes := 0
+    AppFs = fsThatRecordsWrites{Fs: afero.NewOsFs(), writes: &writes}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // When
+    err := ShredFile("/dev/null", Options{Remove: true})
+
+    // Then
+    if !errors.Is(err, ErrRemove) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrRemove, err)
+    }
+    if writes != 0 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 0, writes)
+    }
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package shredder
+
+// syncDir does nothing where directories cannot be opened for syncing.
+// NTFS journals renames and creates itself.
+func syncDir(dir string) error {
+    return nil
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package shredder
+
+// syncDir flushes dir's entries to disk, so a file created or renamed
+// in it is still there after a crash
+func syncDir(dir string) error {
+    file, err := AppFs.Open(dir)
+    if err != nil {
+        return wrapError(ErrSync, err)
+    }
+
+    err = file.Sync()
+    closeErr := file.Close()
+    if err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        return wrapError(ErrSync, err)
+    }
+
+    return nil
+}
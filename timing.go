@@ -0,0 +1,93 @@
+package shredder
+
+import (
+    "context"
+    "runtime/pprof"
+    "time"
+)
+
+// The stages of an overwrite pass, as used for pprof labels
+const (
+    StageGenerate = "generate"
+    StageWrite    = "write"
+    StageSync     = "sync"
+    StageVerify   = "verify"
+)
+
+// StageTimings records the total time spent in each stage across all
+// passes of the operations it is passed to
+type StageTimings struct {
+    Generate time.Duration
+    Write    time.Duration
+    Sync     time.Duration
+    Verify   time.Duration
+}
+
+func (timings *StageTimings) add(stage string, elapsed time.Duration) {
+    switch stage {
+    case StageGenerate:
+        timings.Generate += elapsed
+    case StageWrite:
+        timings.Write += elapsed
+    case StageSync:
+        timings.Sync += elapsed
+    case StageVerify:
+        timings.Verify += elapsed
+    }
+}
+
+func (timings *StageTimings) merge(other StageTimings) {
+    timings.Generate += other.Generate
+    timings.Write += other.Write
+    timings.Sync += other.Sync
+    timings.Verify += other.Verify
+}
+
+var stageLabelSets = map[string]pprof.LabelSet{
+    StageGenerate: pprof.Labels("shredder_stage", StageGenerate),
+    StageWrite:    pprof.Labels("shredder_stage", StageWrite),
+    StageSync:     pprof.Labels("shredder_stage", StageSync),
+    StageVerify:   pprof.Labels("shredder_stage", StageVerify),
+}
+
+// profileLabels holds the contexts the goroutine switches between as it
+// moves through the stages. They are derived from the operation's
+// context, so labels the caller set on it are kept, and built once per
+// operation, as stages begin and end for every chunk written.
+type profileLabels struct {
+    base   context.Context
+    stages map[string]context.Context
+}
+
+func newProfileLabels(ctx context.Context) *profileLabels {
+    labels := &profileLabels{base: ctx, stages: make(map[string]context.Context, len(stageLabelSets))}
+    for stage, set := range stageLabelSets {
+        labels.stages[stage] = pprof.WithLabels(ctx, set)
+    }
+
+    return labels
+}
+
+// beginStage labels the goroutine for profiling and notes the start time
+// when the options ask for either
+func (opts Options) beginStage(stage string) time.Time {
+    if opts.labels != nil {
+        pprof.SetGoroutineLabels(opts.labels.stages[stage])
+    }
+
+    if opts.Timings == nil {
+        return time.Time{}
+    }
+
+    return time.Now()
+}
+
+func (opts Options) endStage(stage string, start time.Time) {
+    if opts.labels != nil {
+        pprof.SetGoroutineLabels(opts.labels.base)
+    }
+
+    if opts.Timings != nil {
+        opts.Timings.add(stage, time.Since(start))
+    }
+}
@@ -0,0 +1,145 @@
+package shredder
+
+import (
+    "bytes"
+    "context"
+    "github.com/spf13/afero"
+    "runtime/pprof"
+    "slices"
+    "strings"
+    "testing"
+    "time"
+)
+
+// Takes a measurable amount of time over every write and sync
+type slowWriter struct {
+    buf *bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (n int, err error) {
+    time.Sleep(time.Millisecond)
+    return w.buf.Write(p)
+}
+
+func (w *slowWriter) Sync() error {
+    time.Sleep(time.Millisecond)
+    return nil
+}
+
+func (w *slowWriter) Seek(offset int64, whence int) (int64, error) {
+    return 0, nil
+}
+
+func TestOverwriteStreamRecordsStageTimings(t *testing.T) {
+    // Given
+    timings := &StageTimings{}
+    writer := &slowWriter{buf: &bytes.Buffer{}}
+
+    // When
+    err := OverwriteStream(writer, 1024, Options{Passes: 2, Timings: timings})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if timings.Write < 2*time.Millisecond || timings.Sync < 2*time.Millisecond {
+        t.Errorf("Test failed, expected write and sync to take at least 2ms, got:  '%v' and '%v'",
+            timings.Write, timings.Sync)
+    }
+    if timings.Generate <= 0 {
+        t.Errorf("Test failed, expected generate time to be recorded, got:  '%v'", timings.Generate)
+    }
+}
+
+// goroutineLabels returns the label sets of every goroutine, as shown in
+// a goroutine profile
+func goroutineLabels() []string {
+    var profile bytes.Buffer
+    pprof.Lookup("goroutine").WriteTo(&profile, 1)
+
+    var labels []string
+    for _, line := range strings.Split(profile.String(), "\n") {
+        if strings.HasPrefix(line, "# labels: ") {
+            labels = append(labels, strings.TrimPrefix(line, "# labels: "))
+        }
+    }
+    return labels
+}
+
+// Records the goroutine labels in place while it fills
+type passThatRecordsLabels struct {
+    labels *[]string
+}
+
+func (pass passThatRecordsLabels) Fill(buf []byte, offset int64) error {
+    *pass.labels = append(*pass.labels, goroutineLabels()...)
+    return nil
+}
+
+func TestOverwriteStreamWithProfileLabelsKeepsCallerLabels(t *testing.T) {
+    // Given
+    ctx := pprof.WithLabels(context.Background(), pprof.Labels("caller", "shredder_test"))
+    pprof.SetGoroutineLabels(ctx)
+    defer pprof.SetGoroutineLabels(context.Background())
+
+    var during []string
+    writer := &WriterThatRecordsBytesWritten{buf: &bytes.Buffer{}, bytesWritten: [][]byte{}}
+    scheme := Scheme{Passes: []Pass{passThatRecordsLabels{labels: &during}}}
+
+    // When
+    err := OverwriteStreamContext(ctx, writer, 16, Options{Scheme: scheme, ProfileLabels: true})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    expected := `{"caller":"shredder_test", "shredder_stage":"generate"}`
+    if !slices.Contains(during, expected) {
+        t.Errorf("Test failed, expected: '%s', got:  '%v'", expected, during)
+    }
+    after := goroutineLabels()
+    if !slices.Contains(after, `{"caller":"shredder_test"}`) {
+        t.Errorf("Test failed, expected the caller's labels restored, got:  '%v'", after)
+    }
+}
+
+func TestShredFileWithProfileLabelsLeavesCallerLabelsAlone(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    var after []string
+
+    // When
+    pprof.Do(context.Background(), pprof.Labels("caller", "shredder_test"), func(context.Context) {
+        ShredFile("test.txt", Options{ProfileLabels: true})
+        OverwriteStream(&WriterThatRecordsBytesWritten{buf: &bytes.Buffer{}}, 16, Options{ProfileLabels: true})
+        after = goroutineLabels()
+    })
+
+    // Then
+    if !slices.Contains(after, `{"caller":"shredder_test"}`) {
+        t.Errorf("Test failed, expected the caller's labels kept, got:  '%v'", after)
+    }
+}
+
+func TestShredDirAddsUpTimingsFromEveryWorker(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    timings := &StageTimings{}
+
+    // When
+    err := ShredDir("/tree", Options{Workers: 8, Verify: true, Timings: timings})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if timings.Generate <= 0 || timings.Write <= 0 || timings.Verify <= 0 {
+        t.Errorf("Test failed, expected every stage to be timed, got:  '%+v'", *timings)
+    }
+}
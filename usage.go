@@ -0,0 +1,62 @@
+package shredder
+
+import (
+    "io"
+    "time"
+)
+
+// ResourceUsage records what the operations it is passed to cost, for
+// modelling large wipes from real runs
+type ResourceUsage struct {
+    // Calls made on the stream. For files each is one system call.
+    Writes int64
+    Reads  int64
+    Syncs  int64
+
+    BytesWritten int64
+    BytesRead    int64
+
+    // Bytes made durable by a successful sync
+    BytesSynced int64
+
+    // The largest chunk buffer allocated
+    PeakBufferBytes int64
+
+    // CPU time used by the whole process while the operations ran, so it
+    // includes any other work going on at the same time. It is zero
+    // where the platform does not report it.
+    CPUTime time.Duration
+}
+
+// merge adds other's counts, leaving CPUTime alone: files shredded at
+// the same time each saw the whole process's CPU time, so adding theirs
+// up would count it several times over
+func (usage *ResourceUsage) merge(other ResourceUsage) {
+    usage.Writes += other.Writes
+    usage.Reads += other.Reads
+    usage.Syncs += other.Syncs
+    usage.BytesWritten += other.BytesWritten
+    usage.BytesRead += other.BytesRead
+    usage.BytesSynced += other.BytesSynced
+    usage.PeakBufferBytes = max(usage.PeakBufferBytes, other.PeakBufferBytes)
+}
+
+// addCPUSince adds the process CPU time used since start, a reading of
+// processCPUTime. Deferring it with the current reading covers the rest
+// of the function.
+func (usage *ResourceUsage) addCPUSince(start time.Duration) {
+    usage.CPUTime += processCPUTime() - start
+}
+
+// Counts every read made while verifying
+type countingReader struct {
+    reader io.Reader
+    usage  *ResourceUsage
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+    n, err := r.reader.Read(p)
+    r.usage.Reads++
+    r.usage.BytesRead += int64(n)
+    return n, err
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package shredder
+
+import "time"
+
+// processCPUTime cannot read CPU time on this platform
+func processCPUTime() time.Duration {
+    return 0
+}
@@ -0,0 +1,55 @@
+package shredder
+
+import (
+    "github.com/spf13/afero"
+    "testing"
+)
+
+func TestOverwriteStreamRecordsResourceUsage(t *testing.T) {
+    // Given
+    file, _ := afero.NewMemMapFs().Create("/file")
+    file.Write(make([]byte, 32))
+    usage := &ResourceUsage{}
+
+    // When
+    err := OverwriteStream(file, 32, Options{Passes: 2, BufferSize: 8, Verify: true, Usage: usage})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    expected := ResourceUsage{
+        Writes: 8, Reads: 8, Syncs: 2,
+        BytesWritten: 64, BytesRead: 64, BytesSynced: 64,
+        PeakBufferBytes: 8,
+    }
+    actual := *usage
+    actual.CPUTime = 0
+    if actual != expected {
+        t.Errorf("Test failed, expected: '%+v', got:  '%+v'", expected, actual)
+    }
+}
+
+func TestShredDirAddsUpUsageFromEveryWorker(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    createTestTree(t)
+    var total int64
+    for _, content := range testTree {
+        total += int64(len(content))
+    }
+    usage := &ResourceUsage{}
+
+    // When
+    err := ShredDir("/tree", Options{Passes: 1, Workers: 8, Usage: usage})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if usage.Writes != int64(len(testTree)) || usage.BytesWritten != total || usage.BytesSynced != total {
+        t.Errorf("Test failed, expected: '%d' writes of '%d' bytes, got:  '%+v'", len(testTree), total, *usage)
+    }
+}
@@ -0,0 +1,267 @@
+package shredder
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "math/rand/v2"
+)
+
+// A BlockVerification is the result of reading back one block of an
+// overwritten range. Blocks are the same size as the chunks written.
+type BlockVerification struct {
+    // Position of the block relative to the start of the range
+    Offset int64
+    Length int64
+
+    // Shannon entropy of the bytes read back, in bits per byte
+    Entropy float64
+
+    // Whether the block read back exactly as the final pass wrote it
+    MatchesWritten bool
+
+    // Whether the block still hashes the same as before shredding. A
+    // block whose contents happened to equal the final pass, such as
+    // zeros overwritten with zeros, cannot be told apart from one that
+    // was never written, so it counts as a failure too.
+    MatchesOriginal bool
+}
+
+func (block BlockVerification) Passed() bool {
+    return block.MatchesWritten && !block.MatchesOriginal
+}
+
+// A VerificationReport holds the per-block evidence that an overwrite
+// took effect
+type VerificationReport struct {
+    // The blocks read back; with sampling, only those chosen
+    Blocks []BlockVerification
+
+    // How the blocks were chosen, with the seed that was used, and the
+    // percentage of the range's bytes they cover
+    Sampling Sampling
+    Coverage float64
+}
+
+// Strategies for choosing which blocks verification reads back
+const (
+    SampleAll      = "all"
+    SampleEveryNth = "every-nth"
+    SampleRandom   = "random"
+    SampleEnds     = "ends"
+)
+
+// A Sampling chooses which blocks verification reads back, trading
+// certainty for read I/O on large files. The zero value reads every
+// block.
+type Sampling struct {
+    // One of the Sample strategies; empty means SampleAll
+    Strategy string
+
+    // With SampleEveryNth, read every Nth block from the first. With
+    // SampleRandom, read N blocks chosen at random, and with SampleEnds
+    // the first and last blocks and N chosen at random between them.
+    N int
+
+    // Seeds the random choice, so it can be repeated. Zero means a new
+    // random seed, which the report records.
+    Seed uint64
+}
+
+// choose picks which of a range's blocks to read back, returning the
+// sampling with its strategy and seed filled in
+func (sampling Sampling) choose(blocks int) ([]bool, Sampling, error) {
+    selected := make([]bool, blocks)
+
+    switch sampling.Strategy {
+    case "", SampleAll:
+        sampling.Strategy = SampleAll
+        for i := range selected {
+            selected[i] = true
+        }
+
+    case SampleEveryNth:
+        if sampling.N <= 0 {
+            return nil, sampling, fmt.Errorf("%w: every-nth sampling needs N above zero", ErrVerify)
+        }
+        for i := 0; i < blocks; i += sampling.N {
+            selected[i] = true
+        }
+
+    case SampleRandom, SampleEnds:
+        if sampling.N < 0 || (sampling.Strategy == SampleRandom && sampling.N == 0) {
+            return nil, sampling, fmt.Errorf("%w: %s sampling needs N above zero", ErrVerify, sampling.Strategy)
+        }
+
+        if sampling.Seed == 0 {
+            seed, err := generateRandomBytes(8)
+            if err != nil {
+                return nil, sampling, err
+            }
+            sampling.Seed = binary.LittleEndian.Uint64(seed)
+        }
+
+        middle := selected
+        if sampling.Strategy == SampleEnds && blocks > 0 {
+            selected[0] = true
+            selected[blocks-1] = true
+            middle = selected[1:max(blocks-1, 1)]
+        }
+
+        picks := rand.New(rand.NewPCG(sampling.Seed, 0)).Perm(len(middle))
+        for _, i := range picks[:min(sampling.N, len(middle))] {
+            middle[i] = true
+        }
+
+    default:
+        return nil, sampling, fmt.Errorf("%w: unknown sampling strategy %q", ErrVerify, sampling.Strategy)
+    }
+
+    return selected, sampling, nil
+}
+
+// fraction is roughly how much of a range of this many blocks the
+// sampling reads back
+func (sampling Sampling) fraction(blocks int64) float64 {
+    if blocks == 0 {
+        return 1
+    }
+
+    var chosen int64
+    switch sampling.Strategy {
+    case SampleEveryNth:
+        chosen = (blocks + int64(max(sampling.N, 1)) - 1) / int64(max(sampling.N, 1))
+    case SampleRandom:
+        chosen = int64(sampling.N)
+    case SampleEnds:
+        chosen = int64(sampling.N) + 2
+    default:
+        chosen = blocks
+    }
+
+    return float64(min(max(chosen, 0), blocks)) / float64(blocks)
+}
+
+func (report VerificationReport) Passed() bool {
+    return len(report.Failures()) == 0
+}
+
+// Failures returns the blocks which did not verify
+func (report VerificationReport) Failures() []BlockVerification {
+    var failures []BlockVerification
+    for _, block := range report.Blocks {
+        if !block.Passed() {
+            failures = append(failures, block)
+        }
+    }
+
+    return failures
+}
+
+// readBlockHashes reads the selected blocks of length bytes from the
+// current position, returning the hash of each at its block's index
+func readBlockHashes(ctx context.Context, stream io.Writer, length int64, buffer []byte, selected []bool, opts Options) ([][sha256.Size]byte, error) {
+    hashes := make([][sha256.Size]byte, len(selected))
+
+    err := readBlocks(ctx, stream, length, buffer, selected, opts, func(index int, offset int64, block []byte) {
+        hashes[index] = sha256.Sum256(block)
+    })
+
+    return hashes, err
+}
+
+// verifyBlocks reads the selected blocks of the range back and compares
+// each with what was there originally and what the final pass wrote
+func verifyBlocks(ctx context.Context, stream io.Writer, length int64, buffer []byte, selected []bool,
+    originalHashes, writtenHashes [][sha256.Size]byte, opts Options) (VerificationReport, error) {
+
+    report := VerificationReport{Coverage: 100}
+    var verified int64
+
+    err := readBlocks(ctx, stream, length, buffer, selected, opts, func(index int, offset int64, block []byte) {
+        hash := sha256.Sum256(block)
+        verified += int64(len(block))
+
+        report.Blocks = append(report.Blocks, BlockVerification{
+            Offset:          offset,
+            Length:          int64(len(block)),
+            Entropy:         ShannonEntropy(block),
+            MatchesWritten:  index < len(writtenHashes) && hash == writtenHashes[index],
+            MatchesOriginal: index < len(originalHashes) && hash == originalHashes[index],
+        })
+    })
+
+    if length > 0 {
+        report.Coverage = 100 * float64(verified) / float64(length)
+    }
+
+    return report, err
+}
+
+// readBlocks reads the selected buffer-sized blocks of length bytes
+// from the current position, seeking over the rest
+func readBlocks(ctx context.Context, stream io.Writer, length int64, buffer []byte, selected []bool, opts Options,
+    visit func(index int, offset int64, block []byte)) error {
+
+    reader, ok := stream.(io.Reader)
+    if !ok {
+        return fmt.Errorf("%w: stream cannot be read back", ErrVerify)
+    }
+
+    if opts.Usage != nil {
+        reader = countingReader{reader: reader, usage: opts.Usage}
+    }
+
+    // Skipped blocks are passed over with a single seek before the next
+    // block read
+    var skip int64
+    for index, read := 0, int64(0); read < length; index++ {
+        if err := interrupted(ctx); err != nil {
+            return err
+        }
+
+        block := buffer[:min(int64(len(buffer)), length-read)]
+        if !selected[index] {
+            skip += int64(len(block))
+            read += int64(len(block))
+            continue
+        }
+
+        if skip > 0 {
+            err := seekForward(stream, skip)
+            if err != nil {
+                return err
+            }
+            skip = 0
+        }
+
+        start := opts.beginStage(StageVerify)
+        _, err := io.ReadFull(reader, block)
+        opts.endStage(StageVerify, start)
+
+        if err != nil {
+            return wrapError(ErrRead, err)
+        }
+
+        visit(index, read, block)
+        read += int64(len(block))
+    }
+
+    return nil
+}
+
+func seekForward(stream io.Writer, offset int64) error {
+    seeker, ok := stream.(io.Seeker)
+    if !ok {
+        return ErrNotSeekable
+    }
+
+    _, err := seeker.Seek(offset, io.SeekCurrent)
+    if err != nil {
+        return wrapError(ErrSeek, err)
+    }
+
+    return nil
+}
@@ -0,0 +1,244 @@
+package shredder
+
+import (
+    "bytes"
+    "errors"
+    "github.com/spf13/afero"
+    "os"
+    "slices"
+    "testing"
+)
+
+func TestShredFileVerifiesEveryBlock(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    report := &VerificationReport{}
+
+    // When
+    err := ShredFile("test.txt", Options{Verify: true, Verification: report, BufferSize: 8})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if !report.Passed() {
+        t.Errorf("Test failed, expected verification to pass, failures:  '%+v'", report.Failures())
+    }
+    expectedLengths := []int64{8, 8, 8, 6}
+    if len(report.Blocks) != len(expectedLengths) {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", len(expectedLengths), len(report.Blocks))
+    }
+    for i, block := range report.Blocks {
+        if block.Offset != int64(i*8) || block.Length != expectedLengths[i] {
+            t.Errorf("Test failed for block %d, expected: '%d+%d', got:  '%d+%d'",
+                i, i*8, expectedLengths[i], block.Offset, block.Length)
+        }
+    }
+}
+
+// Claims every write succeeded without storing anything, like a device
+// that silently drops writes
+type fsThatDropsWrites struct {
+    afero.Fs
+}
+
+func (fs fsThatDropsWrites) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+    file, err := fs.Fs.OpenFile(name, flag, perm)
+    return fileThatDropsWrites{file}, err
+}
+
+type fileThatDropsWrites struct {
+    afero.File
+}
+
+func (f fileThatDropsWrites) Write(p []byte) (int, error) {
+    return len(p), nil
+}
+
+func TestShredFileVerificationCatchesDroppedWrites(t *testing.T) {
+    memFs := afero.NewMemMapFs()
+    AppFs = fsThatDropsWrites{memFs}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(memFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    report := &VerificationReport{}
+
+    // When
+    err := ShredFile("test.txt", Options{Verify: true, Verification: report})
+
+    // Then
+    if !errors.Is(err, ErrVerify) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrVerify, err)
+    }
+    if len(report.Blocks) != 1 || !report.Blocks[0].MatchesOriginal || report.Blocks[0].MatchesWritten {
+        t.Errorf("Test failed, expected the block to still hold its original contents, got:  '%+v'", report.Blocks)
+    }
+}
+
+func TestShredFileVerificationCatchesCorruptedWrites(t *testing.T) {
+    memFs := afero.NewMemMapFs()
+    AppFs = fsThatCorruptsWrites{memFs}
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(memFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    report := &VerificationReport{}
+    lowerCase := Scheme{Passes: []Pass{PatternPass("abc")}}
+
+    // When
+    err := ShredFile("test.txt", Options{Scheme: lowerCase, Verify: true, Verification: report})
+
+    // Then
+    if !errors.Is(err, ErrVerify) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrVerify, err)
+    }
+    failures := report.Failures()
+    if len(failures) != 1 || failures[0].MatchesWritten || failures[0].MatchesOriginal {
+        t.Errorf("Test failed, expected the block to match neither version, got:  '%+v'", report.Blocks)
+    }
+}
+
+func TestShredFileVerificationFailsWhenContentIsUnchanged(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    // Overwriting zeros with zeros leaves no evidence of the overwrite
+    afero.WriteFile(AppFs, "zeros.bin", make([]byte, 16), 0644)
+
+    // When
+    err := ShredFile("zeros.bin", Options{Scheme: SchemeZero, Verify: true})
+
+    // Then
+    if !errors.Is(err, ErrVerify) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrVerify, err)
+    }
+}
+
+func TestOverwriteStreamVerificationNeedsReadableStream(t *testing.T) {
+    // When
+    err := OverwriteStream(&SeekableWriter{buf: &bytes.Buffer{}}, 8, Options{Verify: true})
+
+    // Then
+    if !errors.Is(err, ErrVerify) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrVerify, err)
+    }
+}
+
+func TestShredFileVerificationIsTimed(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.bin", GenerateRandomBytes(256*1024), 0644)
+    timings := &StageTimings{}
+
+    // When
+    ShredFile("test.bin", Options{Verify: true, Timings: timings})
+
+    // Then
+    if timings.Verify <= 0 {
+        t.Errorf("Test failed, expected verify time to be recorded, got:  '%v'", timings.Verify)
+    }
+}
+
+func TestShredFileVerifiesEveryNthBlock(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+    report := &VerificationReport{}
+    usage := &ResourceUsage{}
+    sampling := Sampling{Strategy: SampleEveryNth, N: 2}
+
+    // When
+    err := ShredFile("test.txt", Options{Verify: true, Verification: report, Usage: usage, BufferSize: 8, Sampling: sampling})
+
+    // Then
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+    if len(report.Blocks) != 2 || report.Blocks[0].Offset != 0 || report.Blocks[1].Offset != 16 {
+        t.Fatalf("Test failed, expected blocks at: '%v', got:  '%+v'", []int{0, 16}, report.Blocks)
+    }
+    if expected := 100 * 16 / 30.0; report.Coverage != expected {
+        t.Errorf("Test failed, expected: '%f', got:  '%f'", expected, report.Coverage)
+    }
+    if report.Sampling != sampling {
+        t.Errorf("Test failed, expected: '%+v', got:  '%+v'", sampling, report.Sampling)
+    }
+    if usage.BytesRead != 32 {
+        t.Errorf("Test failed, expected: '%d', got:  '%d'", 32, usage.BytesRead)
+    }
+}
+
+func sampledOffsets(t *testing.T, sampling Sampling) ([]int64, VerificationReport) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    afero.WriteFile(AppFs, "test.bin", make([]byte, 64*8), 0644)
+    report := &VerificationReport{}
+
+    err := ShredFile("test.bin", Options{Passes: 1, Verify: true, Verification: report, BufferSize: 8, Sampling: sampling})
+    if err != nil {
+        t.Fatalf("Test failed, unexpected error: %v", err)
+    }
+
+    var offsets []int64
+    for _, block := range report.Blocks {
+        offsets = append(offsets, block.Offset)
+    }
+    return offsets, *report
+}
+
+func TestRandomSamplingRecordsSeedAndRepeats(t *testing.T) {
+    // When
+    first, report := sampledOffsets(t, Sampling{Strategy: SampleRandom, N: 5})
+    again, _ := sampledOffsets(t, report.Sampling)
+
+    // Then
+    if len(first) != 5 {
+        t.Fatalf("Test failed, expected: '%d', got:  '%d'", 5, len(first))
+    }
+    if report.Sampling.Seed == 0 {
+        t.Errorf("Test failed, expected the seed to be recorded")
+    }
+    if !slices.Equal(first, again) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", first, again)
+    }
+}
+
+func TestEndsSamplingReadsFirstAndLastBlocks(t *testing.T) {
+    // When
+    offsets, _ := sampledOffsets(t, Sampling{Strategy: SampleEnds, N: 2, Seed: 42})
+
+    // Then
+    if len(offsets) != 4 || offsets[0] != 0 || offsets[3] != 63*8 {
+        t.Errorf("Test failed, expected the first and last blocks and two between, got:  '%v'", offsets)
+    }
+}
+
+func TestUnknownSamplingFailsBeforeWriting(t *testing.T) {
+    AppFs = afero.NewMemMapFs()
+    defer func() { AppFs = afero.NewOsFs() }()
+
+    // Given
+    afero.WriteFile(AppFs, "test.txt", []byte("Some bytes that need replacing"), 0644)
+
+    // When
+    err := ShredFile("test.txt", Options{Verify: true, Sampling: Sampling{Strategy: "sometimes"}})
+
+    // Then
+    if !errors.Is(err, ErrVerify) {
+        t.Errorf("Test failed, expected: '%v', got:  '%v'", ErrVerify, err)
+    }
+    content, _ := afero.ReadFile(AppFs, "test.txt")
+    if string(content) != "Some bytes that need replacing" {
+        t.Errorf("Test failed, expected the file untouched, got:  '%s'", content)
+    }
+}